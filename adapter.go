@@ -0,0 +1,91 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// WithContext adapts a handler taking the request context.Context as first argument into a HandlerFunc.
+func WithContext(handler func(context.Context, *Context)) HandlerFunc {
+	return func(c *Context) {
+		handler(c.Req.Context(), c)
+	}
+}
+
+// WrapErr adapts a handler returning an error into a HandlerFunc. A non nil error is recorded with c.Error,
+// the pending handlers are skipped and, unless the handler already wrote the response, it is answered
+// with the status the error maps to and a {"error": ...} JSON body, see StatusError.
+func WrapErr(handler func(*Context) error) HandlerFunc {
+	return func(c *Context) {
+		if err := handler(c); err != nil {
+			c.renderError(err)
+		}
+	}
+}
+
+// MethodScoped runs the middleware only for the requests with one of the given methods, e.g. a CSRF check
+// for the mutating ones, the other requests go straight to the pending handlers.
+func MethodScoped(methods []string, mw HandlerFunc) HandlerFunc {
+	scoped := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		scoped[strings.ToUpper(method)] = true
+	}
+	return func(c *Context) {
+		if !scoped[c.Req.Method] {
+			c.Next()
+			return
+		}
+		mw(c)
+	}
+}
+
+// WrapF adapts a standard http.HandlerFunc into a HandlerFunc.
+func WrapF(h http.HandlerFunc) HandlerFunc {
+	return func(c *Context) {
+		h(c.Writer, c.Req)
+	}
+}
+
+// HandleStd registers a standard http.HandlerFunc with the given path and method,
+// it runs after the middleware of the group like any other handler.
+func (group *RouterGroup) HandleStd(method, p string, h http.HandlerFunc) {
+	group.Handle(method, p, []HandlerFunc{WrapF(h)})
+}
+
+// WrapMiddleware adapts a standard `func(http.Handler) http.Handler` middleware into a HandlerFunc.
+// The pending handlers run as the next http.Handler, with the request and the writer it is given.
+// If the middleware doesn't call the next handler, the pending handlers are skipped.
+func WrapMiddleware(mw func(http.Handler) http.Handler) HandlerFunc {
+	return func(c *Context) {
+		called := false
+		writer, req := c.Writer, c.Req
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			c.Req = r
+			if rw, ok := w.(ResponseWriter); ok {
+				c.Writer = rw
+			} else {
+				c.Writer = &responseWriter{ResponseWriter: w, status: writer.Status()}
+			}
+			c.Next()
+		})
+		mw(next).ServeHTTP(c.Writer, c.Req)
+		c.Writer, c.Req = writer, req
+		if !called {
+			c.index = AbortIndex
+		}
+	}
+}
+
+// UseStd adds a standard net/http middleware to the group, see WrapMiddleware.
+func (group *RouterGroup) UseStd(mw func(http.Handler) http.Handler) {
+	group.Use(WrapMiddleware(mw))
+}
+
+// WrapH adapts a standard http.Handler into a HandlerFunc.
+func WrapH(h http.Handler) HandlerFunc {
+	return func(c *Context) {
+		h.ServeHTTP(c.Writer, c.Req)
+	}
+}
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type ctxKey string
+
+func TestWithContext(t *testing.T) {
+	var got interface{}
+	e := New()
+	e.GET("/", WithContext(func(ctx context.Context, c *Context) {
+		got = ctx.Value(ctxKey("user"))
+		c.String(200, "ok")
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req = req.WithContext(context.WithValue(req.Context(), ctxKey("user"), "bob"))
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+	if got != "bob" || w.Body.String() != "ok" {
+		t.Errorf("the handler got %v in its context and wrote %q", got, w.Body.String())
+	}
+}
+
+func TestHandleStd(t *testing.T) {
+	var method, path string
+	e := New()
+	api := e.Group("/api")
+	api.HandleStd("POST", "/legacy", func(w http.ResponseWriter, r *http.Request) {
+		method, path = r.Method, r.URL.Path
+		w.Header().Set("X-Legacy", "yes")
+		w.WriteHeader(201)
+		w.Write([]byte("created"))
+	})
+
+	w := performRequest(e, "POST", "/api/legacy", nil)
+	if method != "POST" || path != "/api/legacy" {
+		t.Errorf("the handler got %s %s", method, path)
+	}
+	if w.Code != 201 || w.Body.String() != "created" || w.Header().Get("X-Legacy") != "yes" {
+		t.Errorf("got %d %q with X-Legacy %q", w.Code, w.Body.String(), w.Header().Get("X-Legacy"))
+	}
+}
+
+func TestUseStd(t *testing.T) {
+	setHeader := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Frame-Options", "DENY")
+			next.ServeHTTP(w, r)
+		})
+	}
+	deny := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "forbidden", 403)
+		})
+	}
+	reached := false
+	e := New()
+	api := e.Group("/api")
+	api.UseStd(setHeader)
+	api.GET("/ping", func(c *Context) {
+		c.String(200, "pong")
+	})
+	admin := e.Group("/admin")
+	admin.UseStd(deny)
+	admin.GET("/users", func(c *Context) {
+		reached = true
+	})
+	e.GET("/public", func(c *Context) {
+		c.String(200, "public")
+	})
+
+	w := performRequest(e, "GET", "/api/ping", nil)
+	if w.Body.String() != "pong" || w.Header().Get("X-Frame-Options") != "DENY" {
+		t.Errorf("group: got %q with X-Frame-Options %q", w.Body.String(), w.Header().Get("X-Frame-Options"))
+	}
+	w = performRequest(e, "GET", "/public", nil)
+	if w.Header().Get("X-Frame-Options") != "" {
+		t.Error("the middleware of the group ran outside of it")
+	}
+	w = performRequest(e, "GET", "/admin/users", nil)
+	if w.Code != 403 || reached {
+		t.Errorf("a middleware not calling next: got %d, handler reached %v", w.Code, reached)
+	}
+}
+
+// quotaError knows its own status
+type quotaError struct{}
+
+func (quotaError) Error() string   { return "quota exceeded" }
+func (quotaError) StatusCode() int { return 429 }
+
+func TestWrapErr(t *testing.T) {
+	reached := false
+	e := New()
+	e.GET("/quota", WrapErr(func(c *Context) error {
+		return quotaError{}
+	}), func(c *Context) {
+		reached = true
+	})
+	e.GET("/ok", WrapErr(func(c *Context) error {
+		c.String(200, "ok")
+		return nil
+	}))
+
+	w := performRequest(e, "GET", "/quota", nil)
+	if w.Code != 429 || strings.TrimSpace(w.Body.String()) != `{"error":"quota exceeded"}` {
+		t.Errorf("got %d %q, want the mapped 429", w.Code, w.Body.String())
+	}
+	if reached {
+		t.Error("the pending handler ran after the error")
+	}
+	if w := performRequest(e, "GET", "/ok", nil); w.Code != 200 || w.Body.String() != "ok" {
+		t.Errorf("no error: got %d %q", w.Code, w.Body.String())
+	}
+}
+
+func TestMethodScoped(t *testing.T) {
+	var ran []string
+	e := New()
+	e.Use(MethodScoped([]string{"post", "PUT"}, func(c *Context) {
+		ran = append(ran, c.Req.Method)
+		c.Next()
+	}))
+	e.GET("/items", func(c *Context) {
+		c.String(200, "list")
+	})
+	e.POST("/items", func(c *Context) {
+		c.String(201, "created")
+	})
+
+	if w := performRequest(e, "GET", "/items", nil); w.Body.String() != "list" {
+		t.Errorf("GET: body = %q, want list", w.Body.String())
+	}
+	if w := performRequest(e, "POST", "/items", nil); w.Code != 201 || w.Body.String() != "created" {
+		t.Errorf("POST: got %d %q, want 201 created", w.Code, w.Body.String())
+	}
+	if len(ran) != 1 || ran[0] != "POST" {
+		t.Errorf("the middleware ran for %v, want only POST", ran)
+	}
+}
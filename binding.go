@@ -0,0 +1,663 @@
+package engine
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	MIMEPOSTForm          = "application/x-www-form-urlencoded"
+	MIMEMultipartPOSTForm = "multipart/form-data"
+)
+
+// Bind binds the request into the struct specified as a pointer, choosing the decoder with the Content-Type:
+// JSON, XML or form. Requests without Content-Type, or with one that can't be parsed, are decoded
+// as Engine.DefaultBindContentType, which replaces their Content-Type, except GET requests without one which bind the query.
+func (c *Context) Bind(item interface{}) error {
+	contentType := c.Req.Header.Get("Content-Type")
+	if contentType == "" && c.Req.Method == "GET" {
+		return c.BindQuery(item)
+	}
+	// an empty Content-Type doesn't parse either
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil && c.engine != nil {
+		contentType = c.engine.DefaultBindContentType
+		mediaType, _, _ = mime.ParseMediaType(contentType)
+		// so the form parsing of net/http reads the body too
+		c.Req.Header.Set("Content-Type", contentType)
+	}
+	switch mediaType {
+	case MIMEJSON, "":
+		return c.ParseBody(item)
+	case MIMEXML, "text/xml":
+		return c.BindXML(item)
+	case MIMEPOSTForm, MIMEMultipartPOSTForm:
+		return c.BindForm(item)
+	}
+	return errors.New("can't bind content type " + contentType)
+}
+
+// Parses the body content as XML into the struct specified as a pointer and validates it.
+func (c *Context) BindXML(item interface{}) error {
+	if err := c.Req.Context().Err(); err != nil {
+		return err
+	}
+	if err := xml.NewDecoder(c.Req.Body).Decode(item); err != nil {
+		return err
+	}
+	return finishBinding(c, item)
+}
+
+// Binds the url query into the struct specified as a pointer and validates it.
+// Fields are matched by their `form` tag, then their `json` tag, then their name case-insensitively.
+// The slices take the repeated keys, tags=a&tags=b, or the indexed ones, tags[0]=a&tags[1]=b.
+func (c *Context) BindQuery(item interface{}) error {
+	return bindValues(c, c.Req.URL.Query(), item)
+}
+
+// Binds the route params into the struct specified as a pointer and validates it, like BindQuery.
+func (c *Context) BindParams(item interface{}) error {
+	values := url.Values{}
+	for _, p := range c.Params {
+		values.Add(p.Key, p.Value)
+	}
+	return bindValues(c, values, item)
+}
+
+// Binds the url query and the POST form into the struct specified as a pointer and validates it.
+// Both the urlencoded and the multipart forms are supported, the latter keeping up to Engine.MaxMultipartMemory in memory.
+func (c *Context) BindForm(item interface{}) error {
+	mediaType, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+	if mediaType == MIMEMultipartPOSTForm {
+		if err := c.Req.ParseMultipartForm(c.maxMultipartMemory()); err != nil {
+			return err
+		}
+	} else if err := c.Req.ParseForm(); err != nil {
+		return err
+	}
+	return bindValues(c, c.Req.Form, item)
+}
+
+func (c *Context) maxMultipartMemory() int64 {
+	if c.engine == nil || c.engine.MaxMultipartMemory <= 0 {
+		return DefaultMaxMultipartMemory
+	}
+	return c.engine.MaxMultipartMemory
+}
+
+// BindValues binds the values into the struct specified as a pointer and validates it,
+// the same way the query and form binders do but outside of a request.
+func BindValues(values url.Values, item interface{}) error {
+	return bindValues(nil, values, item)
+}
+
+// bindValues maps and validates item, the fields failing to convert and the ones failing
+// their rules are reported together
+func bindValues(c *Context, values url.Values, item interface{}) error {
+	err := mapForm(item, values, c.bindOptions())
+	errs, ok := err.(BindErrors)
+	if err != nil && !ok {
+		return err
+	}
+	if err := finishBinding(c, item); err != nil {
+		errs = appendBindErrors(errs, err)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// BindAny binds the body into the struct specified as a pointer trying JSON, then a urlencoded form,
+// then XML, whatever the Content-Type says, and validates the first one that decodes.
+// Handy for lenient public APIs, but the whole body is buffered in memory to be decoded several times,
+// prefer ParseBody or BindForm when the format is known.
+func (c *Context) BindAny(item interface{}) error {
+	body, err := ioutil.ReadAll(c.Req.Body)
+	if err != nil {
+		return err
+	}
+	c.Req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	jsonErr := json.Unmarshal(body, item)
+	if jsonErr == nil {
+		markNullPointers(reflect.ValueOf(item), body)
+		return finishBinding(c, item)
+	}
+
+	// markup is never a form, don't let it bind as a bunch of empty keys
+	formErr := errors.New("body is not a form")
+	if trimmed := bytes.TrimSpace(body); len(trimmed) == 0 || !bytes.ContainsAny(trimmed[:1], "{[<") {
+		var values url.Values
+		if values, formErr = url.ParseQuery(string(body)); formErr == nil {
+			if formErr = mapForm(item, values, c.bindOptions()); formErr == nil {
+				return finishBinding(c, item)
+			}
+		}
+	}
+
+	xmlErr := xml.Unmarshal(body, item)
+	if xmlErr == nil {
+		return finishBinding(c, item)
+	}
+	return fmt.Errorf("can't bind body: json: %v; form: %v; xml: %v", jsonErr, formErr, xmlErr)
+}
+
+// fieldDecoders are the decoders registered with Engine.RegisterDecoder, by field type
+type fieldDecoders map[reflect.Type]func(string) (interface{}, error)
+
+// RegisterDecoder registers the function decoding the query, form and route param values
+// into the fields of type t, e.g. a "x,y" point into a Point struct.
+// The value returned by decode must be assignable to t.
+func (engine *Engine) RegisterDecoder(t reflect.Type, decode func(string) (interface{}, error)) {
+	if engine.decoders == nil {
+		engine.decoders = fieldDecoders{}
+	}
+	engine.decoders[t] = decode
+}
+
+// bindOptions are the engine settings used by the form binders
+type bindOptions struct {
+	decoders      fieldDecoders
+	strictIndices bool
+}
+
+func (c *Context) bindOptions() bindOptions {
+	if c == nil || c.engine == nil {
+		return bindOptions{}
+	}
+	return bindOptions{decoders: c.engine.decoders, strictIndices: c.engine.StrictFormIndices}
+}
+
+// mapForm sets the fields of the struct pointed by ptr from the form values
+func mapForm(ptr interface{}, form map[string][]string, opts bindOptions) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("binding element must be a non nil pointer")
+	}
+	v = v.Elem()
+	if v.Kind() != reflect.Struct {
+		return errors.New("binding element must be a pointer to a struct")
+	}
+	return mapStruct(v, form, opts)
+}
+
+// mapStruct sets every field it can, the fields failing to convert are all reported as BindErrors
+func mapStruct(v reflect.Value, form map[string][]string, opts bindOptions) error {
+	decoders := opts.decoders
+	var errs BindErrors
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			// unexported
+			continue
+		}
+		name := field.Tag.Get("form")
+		if name == "-" {
+			continue
+		}
+		fieldValue := v.Field(i)
+		_, decoded := decoders[fieldValue.Type()]
+		if name == "" && fieldValue.Kind() == reflect.Struct && !isTextUnmarshaler(fieldValue) && !decoded {
+			// nested and embedded structs share the same form
+			if err := mapStruct(fieldValue, form, opts); err != nil {
+				errs = appendBindErrors(errs, err)
+			}
+			continue
+		}
+		if field.PkgPath != "" {
+			// an unexported embedded type can't be set, only the exported fields of an embedded struct
+			continue
+		}
+		base, err := fieldBase(field)
+		if err != nil {
+			errs = append(errs, FieldError{Field: formName(field, name), Rule: "base", Message: err.Error()})
+			continue
+		}
+		if fieldValue.Kind() == reflect.Map {
+			if err := setMap(fieldValue, form, formName(field, name), base, decoders); err != nil {
+				errs = appendBindErrors(errs, err)
+			}
+			continue
+		}
+		if isStructSlice(fieldValue.Type()) && decoders[fieldValue.Type().Elem()] == nil {
+			if err := setStructSlice(fieldValue, form, formName(field, name), opts); err != nil {
+				errs = appendBindErrors(errs, err)
+			}
+			continue
+		}
+		if fieldValue.Kind() == reflect.Slice {
+			if found, err := setIndexedSlice(fieldValue, form, formName(field, name), base, opts); found {
+				if err != nil {
+					errs = appendBindErrors(errs, err)
+				}
+				continue
+			}
+		}
+		values, ok := lookupFormKey(form, field, name)
+		if !ok {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		if err := setField(fieldValue, values, base, decoders); err != nil {
+			errs = append(errs, FieldError{Field: name, Rule: "type", Message: fmt.Sprintf("field %s: %v", name, err)})
+		}
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// lookupFormKey returns the values of a field: by its form tag if any, else by its json tag name,
+// else by its name matched case-insensitively like encoding/json does.
+func lookupFormKey(form map[string][]string, field reflect.StructField, name string) ([]string, bool) {
+	if name != "" {
+		values, ok := form[name]
+		return values, ok
+	}
+	if tag, skip := jsonName(field); skip {
+		return nil, false
+	} else if tag != "" {
+		values, ok := form[tag]
+		return values, ok
+	}
+	if values, ok := form[field.Name]; ok {
+		return values, true
+	}
+	for key, values := range form {
+		if strings.EqualFold(key, field.Name) {
+			return values, true
+		}
+	}
+	return nil, false
+}
+
+// formName returns the form tag, else the json tag name, else the name of the field
+func formName(field reflect.StructField, tag string) string {
+	if tag != "" {
+		return tag
+	}
+	if j, _ := jsonName(field); j != "" {
+		return j
+	}
+	return field.Name
+}
+
+// jsonName returns the name of the json tag of the field without its options, and whether the tag is "-"
+func jsonName(field reflect.StructField) (name string, skip bool) {
+	name = field.Tag.Get("json")
+	if i := strings.Index(name, ","); i >= 0 {
+		name = name[:i]
+	}
+	if name == "-" {
+		return "", true
+	}
+	return name, false
+}
+
+// setMap fills a map with string keys from the bracketed form keys, `attrs[color]=red` sets attrs["color"].
+// Like mapStruct, every entry failing to convert is reported.
+func setMap(v reflect.Value, form map[string][]string, name string, base int, decoders fieldDecoders) error {
+	if v.Type().Key().Kind() != reflect.String {
+		return FieldError{Field: name, Rule: "type", Message: fmt.Sprintf("field %s: unsupported map key type %s", name, v.Type().Key())}
+	}
+	prefix := name + "["
+	var errs BindErrors
+	for _, key := range prefixedKeys(form, prefix) {
+		if !strings.HasSuffix(key, "]") || len(form[key]) == 0 {
+			continue
+		}
+		mapKey := key[len(prefix) : len(key)-1]
+		if strings.ContainsAny(mapKey, "[]") {
+			continue
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := setField(elem, form[key], base, decoders); err != nil {
+			errs = append(errs, FieldError{Field: key, Rule: "type", Message: fmt.Sprintf("field %s: %v", key, err)})
+			continue
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		v.SetMapIndex(reflect.ValueOf(mapKey).Convert(v.Type().Key()), elem)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// prefixedKeys returns the keys of form starting with prefix, sorted so the errors come in a stable order
+func prefixedKeys(form map[string][]string, prefix string) []string {
+	var keys []string
+	for key := range form {
+		if strings.HasPrefix(key, prefix) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// isStructSlice reports whether t is a slice of structs which don't decode themselves from a string
+func isStructSlice(t reflect.Type) bool {
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Struct &&
+		!reflect.PtrTo(t.Elem()).Implements(textUnmarshalerType)
+}
+
+// the largest index accepted in indexed form keys, since the gaps are allocated
+const maxFormIndex = 1000
+
+// setStructSlice fills a slice of structs from the indexed form keys, `items[0][name]=x&items[1][name]=y`
+// sets the name of two items. Like with setIndexedSlice, the gaps are left to the zero value,
+// or rejected with Engine.StrictFormIndices.
+func setStructSlice(v reflect.Value, form map[string][]string, name string, opts bindOptions) error {
+	prefix := name + "["
+	forms := map[int]map[string][]string{}
+	var errs BindErrors
+	for _, key := range prefixedKeys(form, prefix) {
+		rest := key[len(prefix):]
+		end := strings.Index(rest, "]")
+		if end < 0 {
+			errs = append(errs, FieldError{Field: key, Rule: "index", Message: fmt.Sprintf("field %s: malformed index", key)})
+			continue
+		}
+		index, err := strconv.Atoi(rest[:end])
+		if err != nil || index < 0 || index > maxFormIndex {
+			errs = append(errs, FieldError{Field: key, Rule: "index", Message: fmt.Sprintf("field %s: invalid index %q", key, rest[:end])})
+			continue
+		}
+		// [name] is the key of the item, [tags][0] becomes tags[0]
+		rest = rest[end+1:]
+		end = strings.Index(rest, "]")
+		if !strings.HasPrefix(rest, "[") || end < 0 {
+			errs = append(errs, FieldError{Field: key, Rule: "index", Message: fmt.Sprintf("field %s: missing the item key", key)})
+			continue
+		}
+		if forms[index] == nil {
+			forms[index] = map[string][]string{}
+		}
+		forms[index][rest[1:end]+rest[end+1:]] = form[key]
+	}
+	size := 0
+	for index := range forms {
+		if index >= size {
+			size = index + 1
+		}
+	}
+	if opts.strictIndices && len(forms) != size {
+		errs = append(errs, FieldError{Field: name, Rule: "index", Message: fmt.Sprintf("field %s: missing indices", name)})
+	}
+	if len(forms) > 0 {
+		slice := reflect.MakeSlice(v.Type(), size, size)
+		for index := 0; index < size; index++ {
+			if forms[index] == nil {
+				continue
+			}
+			if err := mapStruct(slice.Index(index), forms[index], opts); err != nil {
+				for _, fe := range appendBindErrors(nil, err) {
+					fe.Field = fmt.Sprintf("%s[%d][%s]", name, index, fe.Field)
+					errs = append(errs, fe)
+				}
+			}
+		}
+		v.Set(slice)
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// setIndexedSlice fills a slice from the indexed form keys, `tags[0]=a&tags[1]=b`, reporting whether there were some.
+// The gaps are left to the zero value, or rejected with Engine.StrictFormIndices.
+func setIndexedSlice(v reflect.Value, form map[string][]string, name string, base int, opts bindOptions) (bool, error) {
+	prefix := name + "["
+	values := map[int]string{}
+	max := -1
+	found := false
+	var errs BindErrors
+	for _, key := range prefixedKeys(form, prefix) {
+		if !strings.HasSuffix(key, "]") || len(form[key]) == 0 {
+			continue
+		}
+		found = true
+		index, err := strconv.Atoi(key[len(prefix) : len(key)-1])
+		if err != nil || index < 0 || index > maxFormIndex {
+			errs = append(errs, FieldError{Field: key, Rule: "index", Message: fmt.Sprintf("field %s: invalid index", key)})
+			continue
+		}
+		values[index] = form[key][0]
+		if index > max {
+			max = index
+		}
+	}
+	if !found {
+		return false, nil
+	}
+	if opts.strictIndices && len(values) != max+1 {
+		errs = append(errs, FieldError{Field: name, Rule: "index", Message: fmt.Sprintf("field %s: missing indices", name)})
+	}
+	if len(values) > 0 {
+		slice := reflect.MakeSlice(v.Type(), max+1, max+1)
+		for index := 0; index <= max; index++ {
+			s, ok := values[index]
+			if !ok {
+				continue
+			}
+			if err := setValue(slice.Index(index), s, base, opts.decoders); err != nil {
+				key := fmt.Sprintf("%s[%d]", name, index)
+				errs = append(errs, FieldError{Field: key, Rule: "type", Message: fmt.Sprintf("field %s: %v", key, err)})
+			}
+		}
+		v.Set(slice)
+	}
+	if len(errs) == 0 {
+		return true, nil
+	}
+	return true, errs
+}
+
+// setField sets v from the values of its form key, slices receive every value
+func setField(v reflect.Value, values []string, base int, decoders fieldDecoders) error {
+	if v.Kind() == reflect.Slice {
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, s := range values {
+			if err := setValue(slice.Index(i), s, base, decoders); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+	if len(values) == 0 {
+		return nil
+	}
+	return setValue(v, values[0], base, decoders)
+}
+
+// fieldBase returns the base of the integers set in the field from its `base` tag, 10 by default.
+// A base of 0 lets the prefix of the value decide, like strconv.ParseInt.
+func fieldBase(field reflect.StructField) (int, error) {
+	tag := field.Tag.Get("base")
+	if tag == "" {
+		return 10, nil
+	}
+	base, err := strconv.Atoi(tag)
+	if err != nil || base == 1 || base < 0 || base > 36 {
+		return 0, fmt.Errorf("field %s: invalid base %q", field.Name, tag)
+	}
+	return base, nil
+}
+
+// intBase handles the 0x prefix of hexadecimal values, which are accepted in base 10 and 16
+func intBase(s string, base int) (string, int) {
+	if base != 10 && base != 16 {
+		return s, base
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		sign, s = s[:1], s[1:]
+	}
+	if len(s) > 2 && s[0] == '0' && (s[1] == 'x' || s[1] == 'X') {
+		return sign + s[2:], 16
+	}
+	return sign + s, base
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// isTextUnmarshaler reports whether v can decode itself from a string, e.g. a time.Time
+func isTextUnmarshaler(v reflect.Value) bool {
+	return v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType)
+}
+
+// setValue converts s to the type of v, using its UnmarshalText method if it has one.
+// Integers are parsed in base, see fieldBase.
+func setValue(v reflect.Value, s string, base int, decoders fieldDecoders) error {
+	if decode, ok := decoders[v.Type()]; ok {
+		decoded, err := decode(s)
+		if err != nil {
+			return err
+		}
+		value := reflect.ValueOf(decoded)
+		if !value.IsValid() || !value.Type().AssignableTo(v.Type()) {
+			return fmt.Errorf("the decoder of %s returned a %T", v.Type(), decoded)
+		}
+		v.Set(value)
+		return nil
+	}
+	if v.Kind() != reflect.Ptr && isTextUnmarshaler(v) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return setValue(v.Elem(), s, base, decoders)
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		// a bare flag (`?verbose`) means true
+		if s == "" {
+			v.SetBool(true)
+			return nil
+		}
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			s = "0"
+		}
+		digits, base := intBase(s, base)
+		i, err := strconv.ParseInt(digits, base, v.Type().Bits())
+		if err != nil {
+			return numberError(err, s, v.Type())
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			s = "0"
+		}
+		digits, base := intBase(s, base)
+		u, err := strconv.ParseUint(digits, base, v.Type().Bits())
+		if err != nil {
+			return numberError(err, s, v.Type())
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			s = "0"
+		}
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return numberError(err, s, v.Type())
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// numberError makes the overflow errors of strconv explicit, e.g. "value 300 out of range for int8"
+func numberError(err error, s string, t reflect.Type) error {
+	if ne, ok := err.(*strconv.NumError); ok && ne.Err == strconv.ErrRange {
+		return fmt.Errorf("value %s out of range for %s", s, t)
+	}
+	return err
+}
+
+// markNullPointers points the nil pointer fields of v whose key is explicitly null in the raw json object
+// to a zero value, so they can be told apart from the absent keys which stay nil.
+func markNullPointers(v reflect.Value, raw []byte) {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		value, ok := lookupJSONKey(object, field)
+		if !ok {
+			continue
+		}
+		fieldValue := v.Field(i)
+		if fieldValue.Kind() == reflect.Ptr && fieldValue.IsNil() && string(bytes.TrimSpace(value)) == "null" {
+			fieldValue.Set(reflect.New(field.Type.Elem()))
+			continue
+		}
+		markNullPointers(fieldValue, value)
+	}
+}
+
+// lookupJSONKey returns the raw value of the field, matching keys the same way encoding/json does
+func lookupJSONKey(object map[string]json.RawMessage, field reflect.StructField) (json.RawMessage, bool) {
+	name := field.Name
+	if tag, skip := jsonName(field); skip {
+		return nil, false
+	} else if tag != "" {
+		name = tag
+	}
+	if value, ok := object[name]; ok {
+		return value, true
+	}
+	for key, value := range object {
+		if strings.EqualFold(key, name) {
+			return value, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,547 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// bindQuery binds the query of url with BindQuery in a request served by e and returns the error
+func bindQuery(e *Engine, url string, item interface{}) error {
+	var err error
+	e.GET("/bind", func(c *Context) {
+		err = c.BindQuery(item)
+	})
+	performRequest(e, "GET", url, nil)
+	return err
+}
+
+func TestBindQueryBoolFlags(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"/bind?debug", true},
+		{"/bind?debug=", true},
+		{"/bind?debug=true", true},
+		{"/bind?debug=1", true},
+		{"/bind?debug=false", false},
+		{"/bind?debug=0", false},
+		{"/bind", false},
+	}
+	for _, tt := range tests {
+		var flags struct {
+			Debug bool `form:"debug"`
+		}
+		if err := bindQuery(New(), tt.url, &flags); err != nil {
+			t.Errorf("%s: %v", tt.url, err)
+			continue
+		}
+		if flags.Debug != tt.want {
+			t.Errorf("%s: debug = %v, want %v", tt.url, flags.Debug, tt.want)
+		}
+	}
+}
+
+func TestParseBodyNullAndAbsentPointers(t *testing.T) {
+	type patch struct {
+		Nickname *string `json:"nickname"`
+	}
+
+	var absent patch
+	if err := bindJSON(New(), `{}`, &absent); err != nil || absent.Nickname != nil {
+		t.Errorf("absent key: got %v, %v, want a nil pointer", absent.Nickname, err)
+	}
+
+	var null patch
+	if err := bindJSON(New(), `{"nickname":null}`, &null); err != nil || null.Nickname == nil || *null.Nickname != "" {
+		t.Errorf("null key: got %v, %v, want a pointer to the zero value", null.Nickname, err)
+	}
+
+	var value patch
+	if err := bindJSON(New(), `{"nickname":"bob"}`, &value); err != nil || value.Nickname == nil || *value.Nickname != "bob" {
+		t.Errorf("value: got %v, %v, want a pointer to bob", value.Nickname, err)
+	}
+}
+
+func TestBindAny(t *testing.T) {
+	type login struct {
+		User string `json:"user" form:"user" xml:"user"`
+		Pass string `json:"pass" form:"pass" xml:"pass"`
+	}
+	tests := []struct {
+		name, contentType, body string
+	}{
+		{"mislabeled json", "text/plain", `{"user":"ann","pass":"secret"}`},
+		{"form", "", "user=ann&pass=secret"},
+		{"xml", "application/json", "<login><user>ann</user><pass>secret</pass></login>"},
+	}
+	for _, tt := range tests {
+		var got login
+		var err error
+		e := New()
+		e.POST("/bind", func(c *Context) {
+			err = c.BindAny(&got)
+		})
+		performRequest(e, "POST", "/bind", strings.NewReader(tt.body), "Content-Type", tt.contentType)
+		if err != nil || got.User != "ann" || got.Pass != "secret" {
+			t.Errorf("%s: got %+v, %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestBindAnyAggregateError(t *testing.T) {
+	var err error
+	e := New()
+	e.POST("/bind", func(c *Context) {
+		var item struct {
+			N int `json:"n" form:"n" xml:"n"`
+		}
+		err = c.BindAny(&item)
+	})
+	performRequest(e, "POST", "/bind", strings.NewReader("{not json"))
+	if err == nil || !strings.Contains(err.Error(), "json:") || !strings.Contains(err.Error(), "xml:") {
+		t.Errorf("got %v, want the errors of every format", err)
+	}
+}
+
+func TestBindValues(t *testing.T) {
+	type filter struct {
+		Name  string   `form:"name" binding:"required"`
+		Page  int      `form:"page"`
+		Tags  []string `form:"tags"`
+		Admin bool     `form:"admin"`
+	}
+	var got filter
+	values := url.Values{"name": {"ann"}, "page": {"3"}, "tags": {"a", "b"}, "admin": {"true"}}
+	if err := BindValues(values, &got); err != nil {
+		t.Fatalf("BindValues returned %v", err)
+	}
+	if got.Name != "ann" || got.Page != 3 || !reflect.DeepEqual(got.Tags, []string{"a", "b"}) || !got.Admin {
+		t.Errorf("got %+v", got)
+	}
+
+	var invalid filter
+	err := BindValues(url.Values{"page": {"x"}}, &invalid)
+	if errs, ok := err.(BindErrors); !ok || len(errs) != 2 {
+		t.Errorf("got %#v, want the page conversion and the required name errors", err)
+	}
+}
+
+func TestBindQueryTagAndNameFallback(t *testing.T) {
+	type search struct {
+		Query    string `form:"q"`
+		PageSize int    `json:"page_size"`
+		Sort     string
+		Ignored  string `json:"-"`
+		Exact    bool
+	}
+	var got search
+	err := bindQuery(New(), "/bind?q=go&page_size=20&sort=asc&Ignored=x&Exact=true&Query=no", &got)
+	if err != nil {
+		t.Fatalf("BindQuery returned %v", err)
+	}
+	want := search{Query: "go", PageSize: 20, Sort: "asc", Exact: true}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestBindFormMaps(t *testing.T) {
+	type product struct {
+		Attrs  map[string]string `form:"attrs"`
+		Stock  map[string]int    `form:"stock"`
+		Prices map[string]int    `form:"prices"`
+	}
+	var got product
+	var err error
+	e := New()
+	e.POST("/bind", func(c *Context) {
+		err = c.BindForm(&got)
+	})
+	body := "attrs[color]=red&attrs[size]=L&stock[paris]=3&prices[a]=x&prices[b]=1&prices[c]=y"
+	performRequest(e, "POST", "/bind", strings.NewReader(body), "Content-Type", "application/x-www-form-urlencoded")
+
+	if !reflect.DeepEqual(got.Attrs, map[string]string{"color": "red", "size": "L"}) {
+		t.Errorf("Attrs = %v", got.Attrs)
+	}
+	if !reflect.DeepEqual(got.Stock, map[string]int{"paris": 3}) {
+		t.Errorf("Stock = %v", got.Stock)
+	}
+	// every bad value is reported and the good ones still bind
+	errs, ok := err.(BindErrors)
+	if !ok || len(errs) != 2 || errs[0].Field != "prices[a]" || errs[1].Field != "prices[c]" {
+		t.Errorf("got %#v, want errors on prices[a] and prices[c]", err)
+	}
+	if got.Prices["b"] != 1 {
+		t.Errorf("Prices = %v", got.Prices)
+	}
+}
+
+func TestParseBodyTypedMap(t *testing.T) {
+	type scores struct {
+		Points map[string]int `json:"points"`
+	}
+	var got scores
+	if err := bindJSON(New(), `{"points":{"ann":3,"bob":5}}`, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Points, map[string]int{"ann": 3, "bob": 5}) {
+		t.Errorf("Points = %v", got.Points)
+	}
+	var bad scores
+	if err := bindJSON(New(), `{"points":{"ann":"three"}}`, &bad); err == nil {
+		t.Error("a string value bound into a map[string]int")
+	}
+}
+
+// userID binds from "u-42" strings
+type userID int
+
+func (id *userID) UnmarshalText(text []byte) error {
+	s := string(text)
+	if !strings.HasPrefix(s, "u-") {
+		return fmt.Errorf("invalid user id %q", s)
+	}
+	n, err := strconv.Atoi(s[2:])
+	*id = userID(n)
+	return err
+}
+
+func TestBindQueryTextUnmarshaler(t *testing.T) {
+	type filter struct {
+		Owner  userID    `form:"owner"`
+		Others []userID  `form:"other"`
+		Since  time.Time `form:"since"`
+	}
+	var got filter
+	if err := bindQuery(New(), "/bind?owner=u-42&other=u-1&other=u-2&since=2020-01-02T00:00:00Z", &got); err != nil {
+		t.Fatalf("BindQuery returned %v", err)
+	}
+	if got.Owner != 42 || !reflect.DeepEqual(got.Others, []userID{1, 2}) || got.Since.Day() != 2 {
+		t.Errorf("got %+v", got)
+	}
+
+	var invalid filter
+	err := bindQuery(New(), "/bind?owner=42", &invalid)
+	if errs, ok := err.(BindErrors); !ok || len(errs) != 1 || errs[0].Field != "owner" {
+		t.Errorf("got %#v, want an error on owner", err)
+	}
+}
+
+func TestBindDefaultContentType(t *testing.T) {
+	type user struct {
+		Name string `json:"name" form:"name"`
+	}
+	bind := func(e *Engine, method, body string, headers ...string) (user, error) {
+		var got user
+		var err error
+		e.Handle(method, "/bind", []HandlerFunc{func(c *Context) {
+			err = c.Bind(&got)
+		}})
+		performRequest(e, method, "/bind?name=query", strings.NewReader(body), headers...)
+		return got, err
+	}
+
+	got, err := bind(New(), "POST", `{"name":"ann"}`)
+	if err != nil || got.Name != "ann" {
+		t.Errorf("JSON without a Content-Type: got %+v, %v", got, err)
+	}
+
+	e := New()
+	e.DefaultBindContentType = MIMEPOSTForm
+	got, err = bind(e, "POST", "name=bob")
+	if err != nil || got.Name != "bob" {
+		t.Errorf("form default: got %+v, %v", got, err)
+	}
+
+	// an unparsable Content-Type falls back to the default too
+	got, err = bind(New(), "POST", `{"name":"ann"}`, "Content-Type", "application/json; charset")
+	if err != nil || got.Name != "ann" {
+		t.Errorf("malformed Content-Type: got %+v, %v", got, err)
+	}
+
+	got, err = bind(New(), "GET", "")
+	if err != nil || got.Name != "query" {
+		t.Errorf("GET without a Content-Type: got %+v, %v", got, err)
+	}
+}
+
+func TestBindQueryIntegerBases(t *testing.T) {
+	type color struct {
+		Red   int    `form:"red"`
+		Green uint8  `form:"green" base:"16"`
+		Mode  int    `form:"mode" base:"8"`
+		Mask  uint32 `form:"mask" base:"0"`
+	}
+	var got color
+	if err := bindQuery(New(), "/bind?red=0xFF&green=a0&mode=755&mask=0b101", &got); err != nil {
+		t.Fatalf("BindQuery returned %v", err)
+	}
+	if want := (color{Red: 255, Green: 160, Mode: 493, Mask: 5}); got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	var decimal color
+	if err := bindQuery(New(), "/bind?red=255&green=0xff", &decimal); err != nil || decimal.Red != 255 || decimal.Green != 255 {
+		t.Errorf("decimal: got %+v, %v", decimal, err)
+	}
+
+	var invalid color
+	if err := bindQuery(New(), "/bind?red=ff", &invalid); err == nil {
+		t.Error("ff bound in base 10")
+	}
+}
+
+func TestBindFormStructSlice(t *testing.T) {
+	type item struct {
+		Name string   `form:"name"`
+		Qty  int      `form:"qty"`
+		Tags []string `form:"tags"`
+	}
+	type order struct {
+		Items []item `form:"items"`
+	}
+	bind := func(e *Engine, body string) (order, error) {
+		var got order
+		var err error
+		e.POST("/bind", func(c *Context) {
+			err = c.BindForm(&got)
+		})
+		performRequest(e, "POST", "/bind", strings.NewReader(body), "Content-Type", MIMEPOSTForm)
+		return got, err
+	}
+
+	// out of order
+	got, err := bind(New(), "items[1][name]=pen&items[0][name]=book&items[0][qty]=2&items[1][tags][0]=blue")
+	want := []item{{Name: "book", Qty: 2}, {Name: "pen", Tags: []string{"blue"}}}
+	if err != nil || !reflect.DeepEqual(got.Items, want) {
+		t.Errorf("got %+v, %v, want %+v", got.Items, err, want)
+	}
+
+	// sparse
+	got, err = bind(New(), "items[0][name]=a&items[2][name]=c")
+	if err != nil || len(got.Items) != 3 || !reflect.DeepEqual(got.Items[1], item{}) || got.Items[2].Name != "c" {
+		t.Errorf("sparse: got %+v, %v", got.Items, err)
+	}
+	e := New()
+	e.StrictFormIndices = true
+	if _, err := bind(e, "items[0][name]=a&items[2][name]=c"); err == nil {
+		t.Error("strict: the gap was accepted")
+	}
+
+	for _, body := range []string{"items[x][name]=a", "items[0=a", "items[0]=a", "items[5000][name]=a"} {
+		if _, err := bind(New(), body); err == nil {
+			t.Errorf("%s: the malformed index was accepted", body)
+		}
+	}
+}
+
+// point binds from "x,y" strings with a registered decoder
+type point struct {
+	X, Y int
+}
+
+func decodePoint(s string) (interface{}, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid point %q", s)
+	}
+	x, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	y, err := strconv.Atoi(parts[1])
+	return point{x, y}, err
+}
+
+func TestRegisterDecoder(t *testing.T) {
+	type route struct {
+		From point   `form:"from"`
+		Via  []point `form:"via"`
+		To   *point  `form:"to"`
+	}
+	e := New()
+	e.RegisterDecoder(reflect.TypeOf(point{}), decodePoint)
+	var got route
+	if err := bindQuery(e, "/bind?from=1,2&via=3,4&via=5,6&to=7,8", &got); err != nil {
+		t.Fatalf("BindQuery returned %v", err)
+	}
+	if got.From != (point{1, 2}) || !reflect.DeepEqual(got.Via, []point{{3, 4}, {5, 6}}) || got.To == nil || *got.To != (point{7, 8}) {
+		t.Errorf("got %+v", got)
+	}
+
+	e = New()
+	e.RegisterDecoder(reflect.TypeOf(point{}), decodePoint)
+	var invalid route
+	err := bindQuery(e, "/bind?from=1", &invalid)
+	if errs, ok := err.(BindErrors); !ok || len(errs) != 1 || errs[0].Field != "from" {
+		t.Errorf("got %#v, want an error on from", err)
+	}
+}
+
+func TestBindQueryIntegerOverflow(t *testing.T) {
+	type limits struct {
+		Level int8   `form:"level"`
+		Port  uint16 `form:"port"`
+		Count int32  `form:"count"`
+	}
+	var valid limits
+	if err := bindQuery(New(), "/bind?level=-128&port=65535&count=2147483647", &valid); err != nil {
+		t.Fatalf("in range: got %v", err)
+	}
+	if valid != (limits{Level: -128, Port: 65535, Count: 2147483647}) {
+		t.Errorf("in range: got %+v", valid)
+	}
+
+	var invalid limits
+	err := bindQuery(New(), "/bind?level=99999999999&port=65536&count=1", &invalid)
+	errs, ok := err.(BindErrors)
+	if !ok || len(errs) != 2 || errs[0].Field != "level" || errs[1].Field != "port" {
+		t.Fatalf("got %#v, want out of range errors on level and port", err)
+	}
+	if !strings.Contains(errs[0].Message, "out of range") {
+		t.Errorf("the message %q doesn't say the value is out of range", errs[0].Message)
+	}
+	if invalid.Level != 0 || invalid.Count != 1 {
+		t.Errorf("got %+v, the overflowing value must not wrap", invalid)
+	}
+}
+
+func TestBindQueryIndexedSlice(t *testing.T) {
+	type filter struct {
+		Tags []string `form:"tags"`
+		IDs  []int    `form:"ids"`
+	}
+	tests := []struct {
+		url  string
+		want filter
+	}{
+		{"/bind?tags[0]=a&tags[1]=b", filter{Tags: []string{"a", "b"}}},
+		{"/bind?tags[1]=b&tags[0]=a&ids[0]=7", filter{Tags: []string{"a", "b"}, IDs: []int{7}}},
+		{"/bind?tags=a&tags=b", filter{Tags: []string{"a", "b"}}},
+		// the gaps are left to the zero value
+		{"/bind?ids[0]=1&ids[2]=3", filter{IDs: []int{1, 0, 3}}},
+	}
+	for _, tt := range tests {
+		var got filter
+		if err := bindQuery(New(), tt.url, &got); err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, %v, want %+v", tt.url, got, err, tt.want)
+		}
+	}
+
+	e := New()
+	e.StrictFormIndices = true
+	var got filter
+	if err := bindQuery(e, "/bind?ids[0]=1&ids[2]=3", &got); err == nil {
+		t.Error("strict: the gap was accepted")
+	}
+	if err := bindQuery(New(), "/bind?ids[0]=x", &got); err == nil {
+		t.Error("the invalid item was accepted")
+	}
+}
+
+func TestBindFormMultipart(t *testing.T) {
+	type signup struct {
+		Name string   `form:"name"`
+		Age  int      `form:"age"`
+		Tags []string `form:"tags"`
+	}
+	fields := url.Values{"name": {"ann"}, "age": {"30"}, "tags": {"a", "b"}}
+
+	var multipartBody bytes.Buffer
+	mw := multipart.NewWriter(&multipartBody)
+	for key, values := range fields {
+		for _, value := range values {
+			mw.WriteField(key, value)
+		}
+	}
+	mw.Close()
+
+	tests := []struct {
+		name        string
+		body        io.Reader
+		contentType string
+	}{
+		{"urlencoded", strings.NewReader(fields.Encode()), MIMEPOSTForm},
+		{"multipart", &multipartBody, mw.FormDataContentType()},
+	}
+	want := signup{Name: "ann", Age: 30, Tags: []string{"a", "b"}}
+	for _, tt := range tests {
+		var got signup
+		var err error
+		e := New()
+		e.POST("/bind", func(c *Context) {
+			err = c.BindForm(&got)
+		})
+		performRequest(e, "POST", "/bind", tt.body, "Content-Type", tt.contentType)
+		if err != nil || !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: got %+v, %v, want %+v", tt.name, got, err, want)
+		}
+	}
+}
+
+type (
+	pagination struct {
+		Page  int `form:"page"`
+		Limit int `form:"limit"`
+	}
+	label  string
+	search struct {
+		pagination
+		label
+		Query string `form:"q"`
+	}
+	tagged struct {
+		*label
+	}
+)
+
+func TestBindQueryUnexportedEmbedded(t *testing.T) {
+	var got search
+	err := bindQuery(New(), "/bind?q=go&page=2&limit=10&label=x", &got)
+	if err != nil || got.Query != "go" || got.Page != 2 || got.Limit != 10 {
+		t.Errorf("got %+v, %v, want the fields of the embedded struct set", got, err)
+	}
+	if got.label != "" {
+		t.Errorf("got %+v, the unexported embedded string was set", got)
+	}
+	var ptr tagged
+	if err := bindQuery(New(), "/bind?label=x", &ptr); err != nil || ptr.label != nil {
+		t.Errorf("got %+v, %v, the unexported embedded pointer was set", ptr, err)
+	}
+}
+
+func TestBindIndexErrorsAreAllReported(t *testing.T) {
+	type order struct {
+		IDs   []int `form:"ids"`
+		Items []struct {
+			Qty int `form:"qty"`
+		} `form:"items"`
+	}
+	query := "/bind?ids[b]=1&ids[0]=x&ids[a]=2&ids[1]=3&items[z][qty]=1&items[0][qty]=many&items[y]=2"
+	want := []string{"ids[a]", "ids[b]", "ids[0]", "items[y]", "items[z][qty]", "items[0][qty]"}
+	// the same errors in the same order every time
+	for i := 0; i < 10; i++ {
+		var got order
+		errs, ok := bindQuery(New(), query, &got).(BindErrors)
+		if !ok || len(errs) != len(want) {
+			t.Fatalf("got %v, want %d errors", errs, len(want))
+		}
+		for j, field := range want {
+			if errs[j].Field != field {
+				t.Fatalf("error %d is on %q, want %q", j, errs[j].Field, field)
+			}
+		}
+		if !reflect.DeepEqual(got.IDs, []int{0, 3}) {
+			t.Errorf("IDs = %v, want the valid items set", got.IDs)
+		}
+	}
+}
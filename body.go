@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	// the limit used by Decompress when there is no MaxBodySize middleware
+	DefaultMaxBodySize = 10 << 20
+
+	// the key under which MaxBodySize stores its limit
+	maxBodySizeKey = "maxBodySize"
+
+	// the size of the raw body attached to the binding errors by default
+	DefaultErrorBodyLimit = 1 << 10
+
+	// the memory used by the multipart forms by default, the rest of the files is stored on disk
+	DefaultMaxMultipartMemory = 32 << 20
+)
+
+// ErrBodyTooLarge is returned when reading a request body past its size limit
+var ErrBodyTooLarge = errors.New("http: request body too large")
+
+// MaxBodySize returns a middleware limiting the request body to n bytes, reading past the limit returns an error.
+// When the handlers record that error without writing a response, it answers with a 413 Payload Too Large.
+func MaxBodySize(n int64) HandlerFunc {
+	return func(c *Context) {
+		c.Req.Body = http.MaxBytesReader(c.Writer, c.Req.Body, n)
+		c.Set(maxBodySizeKey, n)
+		c.Next()
+
+		if c.responseStarted() {
+			return
+		}
+		for _, e := range c.Errors {
+			if strings.Contains(e.Err, ErrBodyTooLarge.Error()) {
+				c.bodyTooLarge(nil)
+				return
+			}
+		}
+	}
+}
+
+// isBodyTooLarge reports whether err comes from reading a body past its limit,
+// either from http.MaxBytesReader or Decompress, which share the same message.
+func isBodyTooLarge(err error) bool {
+	return err != nil && strings.Contains(err.Error(), ErrBodyTooLarge.Error())
+}
+
+// bodyTooLarge answers with a 413 and a JSON message, recording err if not nil
+func (c *Context) bodyTooLarge(err error) {
+	if err != nil {
+		c.Error(err, "Operation aborted")
+	}
+	c.renderJSON(http.StatusRequestEntityTooLarge, H{"error": "request body too large"})
+	c.index = AbortIndex
+}
+
+// SetBodyRedactor registers a function masking the sensitive data of the raw bodies before
+// they are attached to the binding errors, e.g. to blank out passwords.
+func (engine *Engine) SetBodyRedactor(redactor func(body []byte) []byte) {
+	engine.bodyRedactor = redactor
+}
+
+// failBinding answers with a 400, the recorded error has the raw body in its Meta, e.g.
+// {"message": "Operation aborted", "body": "{\"name\": 1}", "truncated": false}
+func (c *Context) failBinding(err error) {
+	limit := 0
+	if c.engine != nil {
+		limit = c.engine.ErrorBodyLimit
+	}
+	if limit <= 0 || len(c.rawBody) == 0 {
+		c.Fail(http.StatusBadRequest, err)
+		return
+	}
+	body := c.rawBody
+	if c.engine.bodyRedactor != nil {
+		body = c.engine.bodyRedactor(append([]byte(nil), body...))
+	}
+	truncated := len(body) > limit
+	if truncated {
+		body = body[:limit]
+	}
+	c.Error(err, H{"message": "Operation aborted", "body": string(body), "truncated": truncated})
+	c.Abort(http.StatusBadRequest)
+}
+
+// Returns the body size limit set by the MaxBodySize middleware, DefaultMaxBodySize otherwise.
+func (c *Context) maxBodySize() int64 {
+	if n, ok := c.Keys[maxBodySizeKey].(int64); ok {
+		return n
+	}
+	return DefaultMaxBodySize
+}
+
+// Decompress returns a middleware transparently decompressing gzip encoded request bodies.
+// The decompressed body is limited to the MaxBodySize limit so a small compressed body
+// expanding to gigabytes fails to bind with ErrBodyTooLarge instead of exhausting the memory.
+func Decompress() HandlerFunc {
+	return func(c *Context) {
+		if !strings.EqualFold(c.Req.Header.Get("Content-Encoding"), "gzip") || c.Req.Body == nil {
+			c.Next()
+			return
+		}
+		reader, err := gzip.NewReader(c.Req.Body)
+		if err != nil {
+			c.Fail(http.StatusBadRequest, err)
+			return
+		}
+		defer reader.Close()
+		c.Req.Body = &limitedBody{reader: reader, closer: c.Req.Body, remaining: c.maxBodySize()}
+		c.Req.Header.Del("Content-Encoding")
+		c.Req.Header.Del("Content-Length")
+		c.Req.ContentLength = -1
+		c.Next()
+	}
+}
+
+// limitedBody is like io.LimitReader but fails with ErrBodyTooLarge instead of silently stopping at the limit
+type limitedBody struct {
+	reader    io.Reader
+	closer    io.Closer
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, ErrBodyTooLarge
+	}
+	// read one byte past the limit to know whether it is exceeded
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.reader.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), ErrBodyTooLarge
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.closer.Close()
+}
+
+// countingBody counts the bytes read from the request body
+type countingBody struct {
+	io.ReadCloser
+	n int64
+}
+
+func (b *countingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.n += int64(n)
+	return n, err
+}
+
+// Returns the number of bytes read from the request body so far, as sent by the client:
+// a compressed body is counted before Decompress. It is only complete once the body was read.
+func (c *Context) RequestSize() int64 {
+	if c.reqBody == nil {
+		return 0
+	}
+	return c.reqBody.n
+}
+
+// RequireContentType returns a middleware aborting POST, PUT and PATCH requests with a 415
+// when their media type isn't one of types, e.g. RequireContentType("application/json").
+// Parameters such as the charset are ignored, other methods pass through.
+func RequireContentType(types ...string) HandlerFunc {
+	return func(c *Context) {
+		switch c.Req.Method {
+		case "POST", "PUT", "PATCH":
+		default:
+			c.Next()
+			return
+		}
+		mediaType, _, err := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+		if err == nil {
+			for _, t := range types {
+				if strings.EqualFold(mediaType, t) {
+					c.Next()
+					return
+				}
+			}
+		}
+		c.Fail(http.StatusUnsupportedMediaType, errors.New("unsupported content type "+c.Req.Header.Get("Content-Type")))
+	}
+}
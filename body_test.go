@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestDecompressBomb(t *testing.T) {
+	// 1MB of zeros compress to about a kilobyte
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(`{"data":"`))
+	gz.Write(make([]byte, 1<<20))
+	gz.Write([]byte(`"}`))
+	gz.Close()
+
+	var bindErr error
+	e := New()
+	e.Use(MaxBodySize(64<<10), Decompress())
+	e.POST("/upload", func(c *Context) {
+		var item struct {
+			Data string `json:"data"`
+		}
+		if bindErr = c.ParseBody(&item); bindErr != nil {
+			c.Fail(400, bindErr)
+			return
+		}
+		c.String(200, "ok")
+	})
+
+	w := performRequest(e, "POST", "/upload", &compressed, "Content-Encoding", "gzip", "Content-Type", MIMEJSON)
+	if w.Code != 400 {
+		t.Errorf("status = %d, want 400", w.Code)
+	}
+	if !isBodyTooLarge(bindErr) {
+		t.Errorf("ParseBody returned %v, want ErrBodyTooLarge", bindErr)
+	}
+	if compressed.Len() >= 64<<10 {
+		t.Fatalf("the compressed body is %d bytes, it should be under the limit", compressed.Len())
+	}
+}
+
+func TestDecompressUnderLimit(t *testing.T) {
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(`{"name":"ann"}`))
+	gz.Close()
+
+	e := New()
+	e.Use(Decompress())
+	e.POST("/upload", func(c *Context) {
+		var item struct {
+			Name string `json:"name"`
+		}
+		if c.EnsureBody(&item) {
+			c.String(200, item.Name)
+		}
+	})
+	w := performRequest(e, "POST", "/upload", &compressed, "Content-Encoding", "gzip", "Content-Type", MIMEJSON)
+	if w.Code != 200 || w.Body.String() != "ann" {
+		t.Errorf("got %d %q, want 200 ann", w.Code, w.Body.String())
+	}
+}
+
+func TestRequireContentType(t *testing.T) {
+	e := New()
+	e.Use(RequireContentType(MIMEJSON))
+	e.POST("/items", func(c *Context) {
+		c.String(201, "created")
+	})
+	e.GET("/items", func(c *Context) {
+		c.String(200, "list")
+	})
+
+	tests := []struct {
+		method, contentType string
+		code                int
+	}{
+		{"POST", "application/json", 201},
+		{"POST", "Application/JSON; charset=utf-8", 201},
+		{"POST", "text/xml", 415},
+		{"POST", "", 415},
+		{"GET", "", 200},
+	}
+	for _, tt := range tests {
+		w := performRequest(e, tt.method, "/items", strings.NewReader("{}"), "Content-Type", tt.contentType)
+		if w.Code != tt.code {
+			t.Errorf("%s with %q: status = %d, want %d", tt.method, tt.contentType, w.Code, tt.code)
+		}
+	}
+}
+
+func TestMaxBodySize413(t *testing.T) {
+	e := New()
+	e.Use(MaxBodySize(16))
+	e.POST("/ensure", func(c *Context) {
+		var item map[string]string
+		if c.EnsureBody(&item) {
+			c.String(200, "ok")
+		}
+	})
+	e.POST("/read", func(c *Context) {
+		if _, err := ioutil.ReadAll(c.Req.Body); err != nil {
+			c.Error(err, "reading the upload")
+		}
+	})
+
+	oversized := `{"name":"` + strings.Repeat("x", 64) + `"}`
+	for _, path := range []string{"/ensure", "/read"} {
+		w := performRequest(e, "POST", path, strings.NewReader(oversized), "Content-Type", MIMEJSON)
+		if w.Code != 413 || strings.TrimSpace(w.Body.String()) != `{"error":"request body too large"}` {
+			t.Errorf("POST %s: got %d %q, want a 413", path, w.Code, w.Body.String())
+		}
+	}
+	w := performRequest(e, "POST", "/ensure", strings.NewReader(`{"a":"b"}`), "Content-Type", MIMEJSON)
+	if w.Code != 200 {
+		t.Errorf("small body: status = %d, want 200", w.Code)
+	}
+}
+
+func TestBindingErrorKeepsRawBody(t *testing.T) {
+	serve := func(e *Engine, body string) ErrorMsgs {
+		var errs ErrorMsgs
+		e.POST("/users", func(c *Context) {
+			c.Next()
+			errs = c.Errors
+		}, func(c *Context) {
+			var item struct {
+				Age int `json:"age"`
+			}
+			c.EnsureBody(&item)
+		})
+		w := performRequest(e, "POST", "/users", strings.NewReader(body), "Content-Type", MIMEJSON)
+		if w.Code != 400 {
+			t.Errorf("status = %d, want 400", w.Code)
+		}
+		return errs
+	}
+
+	errs := serve(New(), `{"age":"old"}`)
+	if len(errs) != 1 {
+		t.Fatalf("recorded %d errors, want 1", len(errs))
+	}
+	meta, ok := errs[0].Meta.(H)
+	if !ok || meta["body"] != `{"age":"old"}` || meta["truncated"] != false {
+		t.Fatalf("recorded %#v, want the raw body in the meta", errs)
+	}
+
+	e := New()
+	e.ErrorBodyLimit = 4
+	e.SetBodyRedactor(func(body []byte) []byte {
+		return bytes.Replace(body, []byte("age"), []byte("***"), -1)
+	})
+	if errs = serve(e, `{"age":"old"}`); len(errs) != 1 {
+		t.Fatalf("redacted: recorded %d errors, want 1", len(errs))
+	}
+	meta, _ = errs[0].Meta.(H)
+	if meta["body"] != `{"**` || meta["truncated"] != true {
+		t.Errorf("redacted and truncated meta = %v", meta)
+	}
+
+	e = New()
+	e.ErrorBodyLimit = 0
+	if errs = serve(e, `{"age":"old"}`); len(errs) != 1 {
+		t.Fatalf("disabled: recorded %d errors, want 1", len(errs))
+	}
+	if errs[0].Meta != "Operation aborted" {
+		t.Errorf("disabled: recorded the meta %#v", errs[0].Meta)
+	}
+}
+
+func TestRequestSize(t *testing.T) {
+	var size int64
+	e := New()
+	e.Use(Decompress())
+	e.POST("/upload", func(c *Context) {
+		ioutil.ReadAll(c.Req.Body)
+		size = c.RequestSize()
+		c.NoContent()
+	})
+
+	body := strings.Repeat("x", 1234)
+	performRequest(e, "POST", "/upload", strings.NewReader(body))
+	if size != 1234 {
+		t.Errorf("RequestSize() = %d, want 1234", size)
+	}
+
+	// a compressed body is counted as sent, before Decompress
+	var compressed bytes.Buffer
+	gz := gzip.NewWriter(&compressed)
+	gz.Write([]byte(body))
+	gz.Close()
+	sent := int64(compressed.Len())
+	performRequest(e, "POST", "/upload", &compressed, "Content-Encoding", "gzip")
+	if size != sent {
+		t.Errorf("RequestSize() = %d, want the compressed %d", size, sent)
+	}
+}
@@ -0,0 +1,149 @@
+package engine
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type (
+	// BreakerConfig configures the CircuitBreaker middleware
+	BreakerConfig struct {
+		// MinRequests is the number of requests observed before the failure ratio is considered, 10 by default
+		MinRequests int
+
+		// FailureRatio is the ratio of failed requests tripping the breaker, 0.5 by default
+		FailureRatio float64
+
+		// Interval is the window over which the failure ratio is measured while the breaker is closed,
+		// the counts start over every interval so the past successes don't hide a run of failures. 1m by default
+		Interval time.Duration
+
+		// Cooldown is how long the breaker stays open before letting a trial request through, 30s by default
+		Cooldown time.Duration
+
+		// PerRoute keeps one breaker per registered route instead of a global one
+		PerRoute bool
+	}
+
+	// the state of a single breaker
+	breaker struct {
+		mu       sync.Mutex
+		state    int
+		requests int
+		failures int
+		// the start of the interval of the counts
+		since    time.Time
+		openedAt time.Time
+		trial    bool
+	}
+)
+
+// allow reports whether a request may go through, half-opening the breaker once the cooldown passed.
+func (b *breaker) allow(cooldown time.Duration) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < cooldown {
+			return false
+		}
+		b.state = breakerHalfOpen
+		b.trial = true
+		return true
+	case breakerHalfOpen:
+		// only one trial request at a time
+		if b.trial {
+			return false
+		}
+		b.trial = true
+		return true
+	}
+	return true
+}
+
+// done records the outcome of a request that was allowed through
+func (b *breaker) done(failed bool, config *BreakerConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		b.trial = false
+		if failed {
+			b.state, b.openedAt = breakerOpen, time.Now()
+		} else {
+			b.state, b.requests, b.failures, b.since = breakerClosed, 0, 0, time.Now()
+		}
+		return
+	}
+	if now := time.Now(); now.Sub(b.since) >= config.Interval {
+		b.requests, b.failures, b.since = 0, 0, now
+	}
+	b.requests++
+	if failed {
+		b.failures++
+	}
+	if b.requests >= config.MinRequests && float64(b.failures)/float64(b.requests) >= config.FailureRatio {
+		b.state, b.openedAt = breakerOpen, time.Now()
+		b.requests, b.failures = 0, 0
+	}
+}
+
+// CircuitBreaker returns a middleware that stops calling the handlers when too many requests fail.
+// A request fails when the handlers respond with a status >= 500 or record an error,
+// the breaker trips when FailureRatio of the requests of an Interval failed.
+// Once tripped, requests are answered with a 503 until the cooldown passes, then a single trial
+// request is let through: the breaker closes if it succeeds and opens again otherwise.
+func CircuitBreaker(config BreakerConfig) HandlerFunc {
+	if config.MinRequests <= 0 {
+		config.MinRequests = 10
+	}
+	if config.FailureRatio <= 0 {
+		config.FailureRatio = 0.5
+	}
+	if config.Cooldown <= 0 {
+		config.Cooldown = 30 * time.Second
+	}
+	if config.Interval <= 0 {
+		config.Interval = time.Minute
+	}
+
+	var mu sync.Mutex
+	breakers := map[string]*breaker{}
+	get := func(key string) *breaker {
+		mu.Lock()
+		defer mu.Unlock()
+		b, ok := breakers[key]
+		if !ok {
+			b = &breaker{}
+			breakers[key] = b
+		}
+		return b
+	}
+
+	return func(c *Context) {
+		key := ""
+		if config.PerRoute {
+			key = c.Req.Method + " " + c.FullPath()
+		}
+		b := get(key)
+		if !b.allow(config.Cooldown) {
+			c.Writer.Header().Set("Retry-After", strconv.Itoa(int(config.Cooldown/time.Second)))
+			c.Abort(http.StatusServiceUnavailable)
+			return
+		}
+
+		failed := true
+		defer func() {
+			b.done(failed, &config)
+		}()
+		c.Next()
+		failed = c.Writer.Status() >= 500 || len(c.Errors) > 0
+	}
+}
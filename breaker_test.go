@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTripsAndRecovers(t *testing.T) {
+	failing := true
+	calls := 0
+	e := New()
+	e.Use(CircuitBreaker(BreakerConfig{MinRequests: 3, FailureRatio: 0.5, Cooldown: 30 * time.Millisecond}))
+	e.GET("/", func(c *Context) {
+		calls++
+		if failing {
+			c.Status(500)
+			return
+		}
+		c.String(200, "ok")
+	})
+
+	for i := 0; i < 3; i++ {
+		if w := performRequest(e, "GET", "/", nil); w.Code != 500 {
+			t.Fatalf("request %d: got %d, want the 500 of the handler", i, w.Code)
+		}
+	}
+	w := performRequest(e, "GET", "/", nil)
+	if w.Code != 503 || calls != 3 {
+		t.Fatalf("tripped breaker: got %d after %d calls, want a 503 without calling the handler", w.Code, calls)
+	}
+	if w.Header().Get("Retry-After") == "" {
+		t.Error("no Retry-After header on the 503")
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	failing = false
+	if w := performRequest(e, "GET", "/", nil); w.Code != 200 {
+		t.Fatalf("trial request after the cooldown: got %d, want 200", w.Code)
+	}
+	if w := performRequest(e, "GET", "/", nil); w.Code != 200 || calls != 5 {
+		t.Errorf("closed breaker: got %d after %d calls, want 200", w.Code, calls)
+	}
+}
+
+func TestCircuitBreakerReopensOnFailedTrial(t *testing.T) {
+	e := New()
+	e.Use(CircuitBreaker(BreakerConfig{MinRequests: 1, Cooldown: 20 * time.Millisecond}))
+	e.GET("/", func(c *Context) {
+		c.Status(502)
+	})
+
+	performRequest(e, "GET", "/", nil)
+	time.Sleep(30 * time.Millisecond)
+	if w := performRequest(e, "GET", "/", nil); w.Code != 502 {
+		t.Fatalf("trial request: got %d, want the 502 of the handler", w.Code)
+	}
+	if w := performRequest(e, "GET", "/", nil); w.Code != 503 {
+		t.Errorf("after a failed trial: got %d, want 503", w.Code)
+	}
+}
+
+func TestCircuitBreakerCountsPerInterval(t *testing.T) {
+	failing := false
+	e := New()
+	e.Use(CircuitBreaker(BreakerConfig{MinRequests: 4, Interval: 50 * time.Millisecond, Cooldown: time.Minute}))
+	e.GET("/", func(c *Context) {
+		if failing {
+			c.Status(500)
+			return
+		}
+		c.String(200, "ok")
+	})
+
+	for i := 0; i < 20; i++ {
+		performRequest(e, "GET", "/", nil)
+	}
+	// the successes of the past interval don't count anymore
+	time.Sleep(60 * time.Millisecond)
+	failing = true
+	for i := 0; i < 4; i++ {
+		performRequest(e, "GET", "/", nil)
+	}
+	if w := performRequest(e, "GET", "/", nil); w.Code != 503 {
+		t.Errorf("after a run of failures: got %d, want 503", w.Code)
+	}
+}
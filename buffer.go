@@ -0,0 +1,193 @@
+package engine
+
+import (
+	"bytes"
+	"net/http"
+)
+
+type (
+	// bufferWriter holds the status, headers and body written by the handlers instead of sending them,
+	// until stream() makes it pass everything through. outer is the buffer it writes into, if any.
+	bufferWriter struct {
+		ResponseWriter
+		header    http.Header
+		status    int
+		body      bytes.Buffer
+		streaming bool
+		outer     *bufferWriter
+	}
+
+	// a complete response recorded by a bufferWriter, it can be written as many times as needed
+	recordedResponse struct {
+		status int
+		header http.Header
+		body   []byte
+	}
+
+	// BufferedResponse is a complete response held before being sent, see BufferResponse
+	BufferedResponse struct {
+		Status int
+		Header http.Header
+		Body   []byte
+	}
+)
+
+func (r *recordedResponse) buffered() *BufferedResponse {
+	return &BufferedResponse{Status: r.status, Header: r.header, Body: r.body}
+}
+
+func (r *BufferedResponse) recorded() *recordedResponse {
+	return &recordedResponse{status: r.Status, header: r.Header, body: r.Body}
+}
+
+// BufferResponse returns a middleware holding the whole response of the pending handlers until they return,
+// the callbacks can then inspect and modify its status, header and body before it is sent,
+// e.g. to sign or audit it. The header starts with the one set by the previous middleware.
+// The Content-Length is dropped as the body may change. The callbacks don't see the streamed responses, see SetStreaming.
+func BufferResponse(callbacks ...func(*Context, *BufferedResponse)) HandlerFunc {
+	return func(c *Context) {
+		w := newBufferWriter(c.Writer)
+		for k, v := range c.Writer.Header() {
+			w.header[k] = append([]string(nil), v...)
+		}
+		w.outer = c.buffer
+		c.Writer = w
+		c.buffer = w
+		defer func() {
+			c.Writer = w.ResponseWriter
+			c.buffer = w.outer
+		}()
+		c.Next()
+		c.writeStatusText()
+		if w.streaming {
+			return
+		}
+
+		response := w.response().buffered()
+		for _, callback := range callbacks {
+			callback(c, response)
+		}
+		response.Header.Del("Content-Length")
+		// the header removed by the callbacks must not be sent
+		header := w.ResponseWriter.Header()
+		for k := range header {
+			if _, ok := response.Header[k]; !ok {
+				delete(header, k)
+			}
+		}
+		response.recorded().writeTo(w.ResponseWriter)
+	}
+}
+
+func newBufferWriter(w ResponseWriter) *bufferWriter {
+	return &bufferWriter{ResponseWriter: w, header: http.Header{}}
+}
+
+// stream sends what was buffered so far and stops buffering
+func (w *bufferWriter) stream() {
+	if w.streaming {
+		return
+	}
+	w.streaming = true
+	header := w.ResponseWriter.Header()
+	for k, v := range w.header {
+		header[k] = v
+	}
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+	if w.body.Len() > 0 {
+		w.ResponseWriter.Write(w.body.Bytes())
+	}
+	w.body = bytes.Buffer{}
+}
+
+func (w *bufferWriter) Header() http.Header {
+	if w.streaming {
+		return w.ResponseWriter.Header()
+	}
+	return w.header
+}
+
+func (w *bufferWriter) WriteHeader(code int) {
+	if w.streaming {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if w.status == 0 {
+		w.status = code
+	}
+}
+
+func (w *bufferWriter) Write(data []byte) (int, error) {
+	if w.streaming {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(data)
+}
+
+func (w *bufferWriter) Status() int {
+	if w.streaming {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *bufferWriter) Written() bool {
+	if w.streaming {
+		return w.ResponseWriter.Written()
+	}
+	return w.status != 0
+}
+
+func (w *bufferWriter) Size() int {
+	if w.streaming {
+		return w.ResponseWriter.Size()
+	}
+	return w.body.Len()
+}
+
+// the buffered header is only sent once the handlers are done, so it can always be changed
+func (w *bufferWriter) HeaderWritten() bool {
+	if w.streaming {
+		return w.ResponseWriter.HeaderWritten()
+	}
+	return false
+}
+
+// Flush does nothing until streaming, the buffer is only sent once the handlers are done
+func (w *bufferWriter) Flush() {
+	if w.streaming {
+		w.ResponseWriter.Flush()
+	}
+}
+
+// response returns a copy of what has been buffered so far
+func (w *bufferWriter) response() *recordedResponse {
+	status := w.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	header := make(http.Header, len(w.header))
+	for k, v := range w.header {
+		header[k] = append([]string(nil), v...)
+	}
+	return &recordedResponse{
+		status: status,
+		header: header,
+		body:   append([]byte(nil), w.body.Bytes()...),
+	}
+}
+
+// writeTo sends the recorded response to w
+func (r *recordedResponse) writeTo(w http.ResponseWriter) {
+	header := w.Header()
+	for k, v := range r.header {
+		header[k] = append([]string(nil), v...)
+	}
+	w.WriteHeader(r.status)
+	w.Write(r.body)
+}
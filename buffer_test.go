@@ -0,0 +1,56 @@
+package engine
+
+import (
+	"bytes"
+	"strconv"
+	"testing"
+)
+
+func TestBufferResponse(t *testing.T) {
+	e := New()
+	e.Use(func(c *Context) {
+		c.Writer.Header().Set("X-Request", "1")
+		c.Writer.Header().Set("X-Internal", "secret")
+		c.Next()
+	})
+	e.Use(BufferResponse(func(c *Context, r *BufferedResponse) {
+		if r.Header.Get("X-Request") != "1" {
+			t.Error("the buffered header doesn't start with the one of the previous middleware")
+		}
+		r.Body = bytes.Replace(r.Body, []byte("draft"), []byte("final"), 1)
+		r.Header.Set("X-Signature", strconv.Itoa(len(r.Body)))
+		r.Header.Del("X-Internal")
+		r.Status = 202
+	}))
+	e.GET("/doc", func(c *Context) {
+		c.Writer.Header().Set("Content-Length", "9")
+		c.String(200, "doc draft")
+	})
+
+	w := performRequest(e, "GET", "/doc", nil)
+	if w.Code != 202 || w.Body.String() != "doc final" {
+		t.Errorf("got %d %q, want the modified 202 doc final", w.Code, w.Body.String())
+	}
+	if w.Header().Get("X-Signature") != "9" || w.Header().Get("X-Request") != "1" {
+		t.Errorf("header = %v", w.Header())
+	}
+	if w.Header().Get("X-Internal") != "" || w.Header().Get("Content-Length") != "" {
+		t.Errorf("the removed headers were sent: %v", w.Header())
+	}
+}
+
+func TestBufferResponseStreaming(t *testing.T) {
+	called := false
+	e := New()
+	e.Use(BufferResponse(func(c *Context, r *BufferedResponse) {
+		called = true
+	}))
+	e.GET("/stream", func(c *Context) {
+		c.SetStreaming(true)
+		c.String(200, "chunk")
+	})
+	w := performRequest(e, "GET", "/stream", nil)
+	if w.Body.String() != "chunk" || called {
+		t.Errorf("got %q, callback called %v", w.Body.String(), called)
+	}
+}
@@ -0,0 +1,136 @@
+package engine
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+type (
+	// CacheConfig configures the CacheWithConfig middleware
+	CacheConfig struct {
+		// TTL is how long a response is served from the cache
+		TTL time.Duration
+
+		// KeyFunc returns the cache key of a request, the method and the request URI by default.
+		// The default key ignores the Authorization and Cookie headers: the responses depending on the user
+		// must be marked private or no-store, or get a KeyFunc including the identity of the user.
+		KeyFunc func(*Context) string
+
+		// OnlyStatusOK restricts the cache to 200 responses, otherwise every response below 400 is cached
+		OnlyStatusOK bool
+	}
+
+	cacheEntry struct {
+		response *recordedResponse
+		expires  time.Time
+	}
+
+	responseCache struct {
+		mu        sync.Mutex
+		entries   map[string]cacheEntry
+		lastSweep time.Time
+	}
+)
+
+func (rc *responseCache) get(key string, now time.Time) *recordedResponse {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	entry, ok := rc.entries[key]
+	if !ok {
+		return nil
+	}
+	if now.After(entry.expires) {
+		delete(rc.entries, key)
+		return nil
+	}
+	return entry.response
+}
+
+func (rc *responseCache) set(key string, response *recordedResponse, now time.Time, ttl time.Duration) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	// drop the expired entries from time to time so the cache doesn't grow forever
+	if now.Sub(rc.lastSweep) > ttl {
+		for k, entry := range rc.entries {
+			if now.After(entry.expires) {
+				delete(rc.entries, k)
+			}
+		}
+		rc.lastSweep = now
+	}
+	rc.entries[key] = cacheEntry{response: response, expires: now.Add(ttl)}
+}
+
+// Cache returns a middleware keeping the responses of GET and HEAD requests in memory for ttl.
+// Requests with a cached response are answered without calling the pending handlers.
+// The responses setting a cookie or with a private or no-store Cache-Control aren't cached,
+// and the key doesn't tell the users apart, see CacheConfig.KeyFunc.
+func Cache(ttl time.Duration, keyFunc func(*Context) string) HandlerFunc {
+	return CacheWithConfig(CacheConfig{TTL: ttl, KeyFunc: keyFunc})
+}
+
+// Like Cache() with more options, see CacheConfig.
+func CacheWithConfig(config CacheConfig) HandlerFunc {
+	if config.KeyFunc == nil {
+		config.KeyFunc = func(c *Context) string {
+			return c.Req.Method + " " + c.Req.URL.RequestURI()
+		}
+	}
+	cache := &responseCache{entries: map[string]cacheEntry{}}
+	return func(c *Context) {
+		if c.Req.Method != "GET" && c.Req.Method != "HEAD" {
+			c.Next()
+			return
+		}
+
+		key := config.KeyFunc(c)
+		if response := cache.get(key, time.Now()); response != nil {
+			response.writeTo(c.Writer)
+			c.index = AbortIndex
+			return
+		}
+
+		w := newBufferWriter(c.Writer)
+		c.Writer = w
+		defer func() {
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+		c.writeStatusText()
+
+		response := w.response()
+		response.writeTo(w.ResponseWriter)
+		if cacheable(response.status, config.OnlyStatusOK) && shareable(response.header) && len(c.Errors) == 0 {
+			cache.set(key, response, time.Now(), config.TTL)
+		}
+	}
+}
+
+func cacheable(status int, onlyOK bool) bool {
+	if onlyOK {
+		return status == http.StatusOK
+	}
+	return status < 400
+}
+
+// shareable reports whether a response can be served to other users: it sets no cookie
+// and its Cache-Control doesn't restrict it to the user or forbid storing it
+func shareable(header http.Header) bool {
+	if len(header["Set-Cookie"]) > 0 {
+		return false
+	}
+	for _, value := range header["Cache-Control"] {
+		for _, directive := range strings.Split(value, ",") {
+			directive = strings.ToLower(strings.TrimSpace(directive))
+			if i := strings.IndexByte(directive, '='); i >= 0 {
+				directive = directive[:i]
+			}
+			if directive == "private" || directive == "no-store" {
+				return false
+			}
+		}
+	}
+	return true
+}
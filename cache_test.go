@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCacheHitAndExpiry(t *testing.T) {
+	calls := 0
+	e := New()
+	e.Use(Cache(30*time.Millisecond, nil))
+	e.GET("/", func(c *Context) {
+		calls++
+		c.String(200, fmt.Sprint(calls))
+	})
+
+	if w := performRequest(e, "GET", "/", nil); w.Body.String() != "1" {
+		t.Fatalf("first request got %q", w.Body.String())
+	}
+	if w := performRequest(e, "GET", "/", nil); w.Body.String() != "1" || calls != 1 {
+		t.Errorf("cache hit: got %q after %d calls, want the cached response", w.Body.String(), calls)
+	}
+	if w := performRequest(e, "GET", "/?page=2", nil); w.Body.String() != "2" {
+		t.Errorf("another URI got %q, want a miss", w.Body.String())
+	}
+
+	time.Sleep(40 * time.Millisecond)
+	if w := performRequest(e, "GET", "/", nil); w.Body.String() != "3" {
+		t.Errorf("after expiry: got %q, want a miss", w.Body.String())
+	}
+}
+
+func TestCacheSkipsNonCacheableStatuses(t *testing.T) {
+	tests := []struct {
+		status       int
+		onlyStatusOK bool
+		cached       bool
+	}{
+		{200, false, true},
+		{301, false, true},
+		{404, false, false},
+		{500, false, false},
+		{201, true, false},
+	}
+	for _, tt := range tests {
+		calls := 0
+		e := New()
+		e.Use(CacheWithConfig(CacheConfig{TTL: time.Minute, OnlyStatusOK: tt.onlyStatusOK}))
+		e.GET("/", func(c *Context) {
+			calls++
+			c.String(tt.status, "x")
+		})
+		performRequest(e, "GET", "/", nil)
+		w := performRequest(e, "GET", "/", nil)
+		if w.Code != tt.status {
+			t.Errorf("%d: replayed status %d", tt.status, w.Code)
+		}
+		if cached := calls == 1; cached != tt.cached {
+			t.Errorf("%d (only 200: %v): cached = %v, want %v", tt.status, tt.onlyStatusOK, cached, tt.cached)
+		}
+	}
+}
+
+func TestCacheSkipsPersonalizedResponses(t *testing.T) {
+	tests := []struct {
+		name   string
+		header func(http.Header)
+		cached bool
+	}{
+		{"public", func(h http.Header) { h.Set("Cache-Control", "public, max-age=60") }, true},
+		{"cookie", func(h http.Header) { h.Add("Set-Cookie", "session=ann") }, false},
+		{"private", func(h http.Header) { h.Set("Cache-Control", "max-age=60, Private") }, false},
+		{"no-store", func(h http.Header) { h.Set("Cache-Control", "no-store") }, false},
+		{"private field", func(h http.Header) { h.Set("Cache-Control", `private="X-User"`) }, false},
+	}
+	for _, tt := range tests {
+		calls := 0
+		e := New()
+		e.Use(Cache(time.Minute, nil))
+		e.GET("/me", func(c *Context) {
+			calls++
+			tt.header(c.Writer.Header())
+			c.String(200, fmt.Sprint("user ", calls))
+		})
+		performRequest(e, "GET", "/me", nil)
+		w := performRequest(e, "GET", "/me", nil)
+		if cached := calls == 1; cached != tt.cached {
+			t.Errorf("%s: cached = %v, want %v", tt.name, cached, tt.cached)
+		}
+		if !tt.cached && w.Body.String() != "user 2" {
+			t.Errorf("%s: the second user got %q", tt.name, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package engine
+
+import (
+	"bytes"
+)
+
+const (
+	// the default maximum number of bytes kept by CaptureResponse
+	DefaultCaptureLimit = 1 << 20
+
+	// the key under which the capturing writer is stored in Context.Keys
+	capturedBodyKey = "capturedBody"
+)
+
+// captureWriter tees everything written to the response into a bounded buffer
+type captureWriter struct {
+	ResponseWriter
+	buf   bytes.Buffer
+	limit int
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	if room := w.limit - w.buf.Len(); room > 0 {
+		if len(data) > room {
+			w.buf.Write(data[:room])
+		} else {
+			w.buf.Write(data)
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// CaptureResponse returns a middleware that keeps a copy of the response body, see Context.CapturedBody().
+// At most DefaultCaptureLimit bytes are kept.
+func CaptureResponse() HandlerFunc {
+	return CaptureResponseLimit(DefaultCaptureLimit)
+}
+
+// Like CaptureResponse() but keeps at most limit bytes of the body.
+func CaptureResponseLimit(limit int) HandlerFunc {
+	return func(c *Context) {
+		w := &captureWriter{ResponseWriter: c.Writer, limit: limit}
+		c.Writer = w
+		c.Set(capturedBodyKey, w)
+		defer func() {
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// Returns the response body captured by the CaptureResponse middleware, truncated to its limit.
+// It returns nil if the middleware isn't in the chain.
+func (c *Context) CapturedBody() []byte {
+	if w, ok := c.Keys[capturedBodyKey].(*captureWriter); ok {
+		return w.buf.Bytes()
+	}
+	return nil
+}
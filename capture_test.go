@@ -0,0 +1,39 @@
+package engine
+
+import "testing"
+
+func TestCaptureResponse(t *testing.T) {
+	var captured string
+	e := New()
+	e.Use(func(c *Context) {
+		c.Next()
+		captured = string(c.CapturedBody())
+	})
+	e.Use(CaptureResponse())
+	e.GET("/", func(c *Context) {
+		c.JSON(200, H{"name": "bob"})
+	})
+
+	w := performRequest(e, "GET", "/", nil)
+	if captured != w.Body.String() {
+		t.Errorf("captured %q, the response is %q", captured, w.Body.String())
+	}
+}
+
+func TestCaptureResponseLimit(t *testing.T) {
+	var captured string
+	e := New()
+	e.Use(func(c *Context) {
+		c.Next()
+		captured = string(c.CapturedBody())
+	})
+	e.Use(CaptureResponseLimit(4))
+	e.GET("/", func(c *Context) {
+		c.String(200, "abcdefgh")
+	})
+
+	w := performRequest(e, "GET", "/", nil)
+	if captured != "abcd" || w.Body.String() != "abcdefgh" {
+		t.Errorf("captured %q of %q, want the first 4 bytes of the whole response", captured, w.Body.String())
+	}
+}
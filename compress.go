@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"compress/gzip"
+	"net/http"
+)
+
+// gzipWriter compresses the response body unless the handlers set their own Content-Encoding
+type gzipWriter struct {
+	ResponseWriter
+	gz       *gzip.Writer
+	level    int
+	started  bool
+	compress bool
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	if !w.started {
+		w.started = true
+		header := w.Header()
+		if header.Get("Content-Encoding") == "" && code != http.StatusNoContent && code != http.StatusNotModified {
+			w.compress = true
+			header.Del("Content-Length")
+			header.Set("Content-Encoding", "gzip")
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *gzipWriter) Write(data []byte) (int, error) {
+	if !w.started {
+		// sniff the type from the plain data, net/http would see the compressed one
+		if w.Header().Get("Content-Type") == "" {
+			w.Header().Set("Content-Type", http.DetectContentType(data))
+		}
+		w.WriteHeader(http.StatusOK)
+	}
+	if !w.compress {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.gz == nil {
+		w.gz, _ = gzip.NewWriterLevel(w.ResponseWriter, w.level)
+	}
+	return w.gz.Write(data)
+}
+
+func (w *gzipWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// close ends the gzip stream, an empty body is answered with an empty gzip stream
+func (w *gzipWriter) close() {
+	if !w.compress {
+		return
+	}
+	if w.gz == nil {
+		w.gz, _ = gzip.NewWriterLevel(w.ResponseWriter, w.level)
+	}
+	w.gz.Close()
+}
+
+// Gzip returns a middleware compressing the responses with gzip at the given level, e.g. gzip.DefaultCompression,
+// when the client accepts it. Accept-Encoding is added to the Vary header.
+// Invalid levels fall back to gzip.DefaultCompression.
+func Gzip(level int) HandlerFunc {
+	if level < gzip.HuffmanOnly || level > gzip.BestCompression {
+		level = gzip.DefaultCompression
+	}
+	return func(c *Context) {
+		c.AddVary("Accept-Encoding")
+		if c.Req.Method == "HEAD" || !acceptsGzip(c.Req.Header.Get("Accept-Encoding")) {
+			c.Next()
+			return
+		}
+		w := &gzipWriter{ResponseWriter: c.Writer, level: level}
+		c.Writer = w
+		defer func() {
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+		// the default body of Status() must be compressed too
+		c.writeStatusText()
+		if w.started {
+			w.close()
+		}
+	}
+}
+
+// acceptsGzip reports whether the Accept-Encoding header accepts gzip
+func acceptsGzip(header string) bool {
+	for _, encoding := range parseAccept(header) {
+		if encoding == "gzip" || encoding == "*" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package engine
+
+import (
+	"log"
+)
+
+// contentTypeWriter warns when the body is written before any Content-Type is set
+type contentTypeWriter struct {
+	ResponseWriter
+	c       *Context
+	checked bool
+}
+
+func (w *contentTypeWriter) Write(data []byte) (int, error) {
+	if !w.checked && len(data) > 0 {
+		w.checked = true
+		if w.Header().Get("Content-Type") == "" {
+			log.Printf("WARNING: %s %s: the response body is written without a Content-Type, net/http will sniff it",
+				w.c.Req.Method, w.c.Req.URL.Path)
+		}
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// WarnMissingContentType returns a middleware logging a warning for the responses whose body is written
+// without a Content-Type header, to catch the handlers forgetting it or setting it after writing.
+// It only checks in Debug mode and never changes the response.
+func WarnMissingContentType() HandlerFunc {
+	return func(c *Context) {
+		if !c.engine.Debug {
+			c.Next()
+			return
+		}
+		w := &contentTypeWriter{ResponseWriter: c.Writer, c: c}
+		c.Writer = w
+		defer func() {
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWarnMissingContentType(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+
+	newEngine := func(debug bool) *Engine {
+		e := New()
+		e.Debug = debug
+		e.Use(WarnMissingContentType())
+		e.GET("/raw", func(c *Context) {
+			c.Writer.Write([]byte("hello"))
+		})
+		e.GET("/typed", func(c *Context) {
+			c.String(200, "hello")
+		})
+		return e
+	}
+
+	w := performRequest(newEngine(true), "GET", "/raw", nil)
+	if !strings.Contains(out.String(), "WARNING: GET /raw") {
+		t.Errorf("log = %q, want a warning for /raw", out.String())
+	}
+	if w.Body.String() != "hello" {
+		t.Errorf("body = %q, the response shouldn't change", w.Body.String())
+	}
+
+	out.Reset()
+	performRequest(newEngine(true), "GET", "/typed", nil)
+	performRequest(newEngine(false), "GET", "/raw", nil)
+	if out.Len() != 0 {
+		t.Errorf("log = %q, want no warning with a Content-Type or outside Debug", out.String())
+	}
+}
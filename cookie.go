@@ -0,0 +1,86 @@
+package engine
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// the minimum length of the keys signing the cookies
+const minCookieSecretLength = 32
+
+var (
+	ErrNoCookieSecret   = errors.New("engine: no secret to sign the cookie")
+	ErrInvalidSignature = errors.New("engine: invalid cookie signature")
+)
+
+// SetCookieSecret sets the default key used by SetSignedCookie and SignedCookie.
+// It panics if the key is shorter than 32 bytes.
+func (engine *Engine) SetCookieSecret(key []byte) {
+	if len(key) < minCookieSecretLength {
+		panic("engine: the cookie secret must be at least 32 bytes long")
+	}
+	engine.cookieSecret = append([]byte(nil), key...)
+}
+
+// cookieSecret returns the explicit secret if any, else the one of the engine
+func (c *Context) cookieSecret(secret [][]byte) ([]byte, error) {
+	if len(secret) > 0 && len(secret[0]) > 0 {
+		return secret[0], nil
+	}
+	if c.engine != nil && len(c.engine.cookieSecret) > 0 {
+		return c.engine.cookieSecret, nil
+	}
+	return nil, ErrNoCookieSecret
+}
+
+// SetSignedCookie sets the cookie with its value signed with HMAC-SHA256, so SignedCookie can tell
+// whether the client tampered with it. The value is not encrypted.
+// The engine secret is used unless one is passed.
+func (c *Context) SetSignedCookie(cookie *http.Cookie, secret ...[]byte) error {
+	key, err := c.cookieSecret(secret)
+	if err != nil {
+		return err
+	}
+	signed := *cookie
+	value := base64.RawURLEncoding.EncodeToString([]byte(cookie.Value))
+	signed.Value = value + "." + signCookie(key, cookie.Name, value)
+	http.SetCookie(c.Writer, &signed)
+	return nil
+}
+
+// SignedCookie returns the value of a cookie set by SetSignedCookie, or ErrInvalidSignature
+// when its signature doesn't match. The engine secret is used unless one is passed.
+func (c *Context) SignedCookie(name string, secret ...[]byte) (string, error) {
+	key, err := c.cookieSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	cookie, err := c.Req.Cookie(name)
+	if err != nil {
+		return "", err
+	}
+	i := strings.LastIndex(cookie.Value, ".")
+	if i < 0 {
+		return "", ErrInvalidSignature
+	}
+	value, signature := cookie.Value[:i], cookie.Value[i+1:]
+	if !hmac.Equal([]byte(signature), []byte(signCookie(key, name, value))) {
+		return "", ErrInvalidSignature
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", ErrInvalidSignature
+	}
+	return string(decoded), nil
+}
+
+// signCookie signs the name and the encoded value together so a signed value can't be moved to another cookie
+func signCookie(key []byte, name, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(name + "=" + value))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,60 @@
+package engine
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestSignedCookieRoundTrip(t *testing.T) {
+	e := New()
+	e.SetCookieSecret(bytes.Repeat([]byte("k"), 32))
+	e.GET("/login", func(c *Context) {
+		if err := c.SetSignedCookie(&http.Cookie{Name: "session", Value: "user=ann"}); err != nil {
+			t.Errorf("SetSignedCookie returned %v", err)
+		}
+	})
+	var value string
+	var readErr error
+	e.GET("/me", func(c *Context) {
+		value, readErr = c.SignedCookie("session")
+	})
+
+	w := performRequest(e, "GET", "/login", nil)
+	cookie := w.Header().Get("Set-Cookie")
+	if !strings.HasPrefix(cookie, "session=") {
+		t.Fatalf("Set-Cookie = %q", cookie)
+	}
+	signed := strings.SplitN(strings.TrimPrefix(cookie, "session="), ";", 2)[0]
+
+	performRequest(e, "GET", "/me", nil, "Cookie", "session="+signed)
+	if readErr != nil || value != "user=ann" {
+		t.Errorf("SignedCookie = %q, %v, want user=ann", value, readErr)
+	}
+
+	tampered := "dXNlcj1ib2I" + signed[strings.LastIndex(signed, "."):]
+	performRequest(e, "GET", "/me", nil, "Cookie", "session="+tampered)
+	if readErr != ErrInvalidSignature {
+		t.Errorf("a tampered cookie: got %q, %v, want ErrInvalidSignature", value, readErr)
+	}
+}
+
+func TestSignedCookieSecrets(t *testing.T) {
+	var err error
+	e := New()
+	e.GET("/", func(c *Context) {
+		err = c.SetSignedCookie(&http.Cookie{Name: "a", Value: "b"})
+	})
+	performRequest(e, "GET", "/", nil)
+	if err != ErrNoCookieSecret {
+		t.Errorf("without a secret: got %v, want ErrNoCookieSecret", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("a short secret didn't panic")
+		}
+	}()
+	e.SetCookieSecret([]byte("short"))
+}
@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestCSPNonce(t *testing.T) {
+	e := New()
+	e.HTMLTemplates = template.Must(template.New("page").Parse(`<script nonce="{{.nonce}}"></script>`))
+	e.Use(CSPNonce())
+	e.GET("/", func(c *Context) {
+		c.HTML(200, "page", H{"nonce": c.CSPNonce()})
+	})
+
+	first := performRequest(e, "GET", "/", nil)
+	policy := first.Header().Get("Content-Security-Policy")
+	start := strings.Index(policy, "'nonce-")
+	if start < 0 {
+		t.Fatalf("Content-Security-Policy = %q, want a nonce", policy)
+	}
+	nonce := policy[start+len("'nonce-"):]
+	nonce = nonce[:strings.Index(nonce, "'")]
+	if len(nonce) < 16 {
+		t.Errorf("nonce %q is too short", nonce)
+	}
+	if want := `<script nonce="` + nonce + `"></script>`; first.Body.String() != want {
+		t.Errorf("body = %q, want %q", first.Body.String(), want)
+	}
+
+	second := performRequest(e, "GET", "/", nil)
+	if second.Header().Get("Content-Security-Policy") == policy {
+		t.Error("two requests got the same nonce")
+	}
+}
+
+func TestCSPNonceWithoutMiddleware(t *testing.T) {
+	var nonce = "unset"
+	e := New()
+	e.GET("/", func(c *Context) {
+		nonce = c.CSPNonce()
+	})
+	performRequest(e, "GET", "/", nil)
+	if nonce != "" {
+		t.Errorf("CSPNonce() = %q, want empty", nonce)
+	}
+}
@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// Writes the records as CSV into the response body.
+// Unless the handler already set them, it also sets the Content-Type as "text/csv"
+// and a Content-Disposition asking the browser to download it.
+func (c *Context) CSV(code int, records [][]string) {
+	if c.requestDone() {
+		return
+	}
+	header := c.Writer.Header()
+	c.setContentType("text/csv; charset=utf-8")
+	if header.Get("Content-Disposition") == "" {
+		header.Set("Content-Disposition", "attachment")
+	}
+	if code >= 0 {
+		c.Writer.WriteHeader(code)
+	}
+	writer := csv.NewWriter(c.Writer)
+	if err := writer.WriteAll(records); err != nil {
+		c.Error(err, records)
+	}
+}
+
+// Like CSV() but takes a slice of structs, one row per element.
+// The header row is built from the `csv` tags, then the `json` tags, then the field names.
+// Fields tagged with "-" are skipped.
+func (c *Context) CSVSlice(code int, items interface{}) {
+	records, err := csvRecords(items)
+	if err != nil {
+		c.Error(err, items)
+		http.Error(c.Writer, err.Error(), 500)
+		return
+	}
+	c.CSV(code, records)
+}
+
+func csvRecords(items interface{}) ([][]string, error) {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil, errors.New("csv: items must be a slice of structs")
+	}
+	t := v.Type().Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, errors.New("csv: items must be a slice of structs")
+	}
+
+	var columns []int
+	var header []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := csvColumnName(field)
+		if name == "-" {
+			continue
+		}
+		columns = append(columns, i)
+		header = append(header, name)
+	}
+
+	records := [][]string{header}
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i)
+		for item.Kind() == reflect.Ptr && !item.IsNil() {
+			item = item.Elem()
+		}
+		row := make([]string, len(columns))
+		if item.Kind() == reflect.Struct {
+			for j, column := range columns {
+				row[j] = csvValue(item.Field(column))
+			}
+		}
+		records = append(records, row)
+	}
+	return records, nil
+}
+
+func csvColumnName(field reflect.StructField) string {
+	for _, key := range []string{"csv", "json"} {
+		tag := field.Tag.Get(key)
+		if i := strings.Index(tag, ","); i >= 0 {
+			tag = tag[:i]
+		}
+		if tag != "" {
+			return tag
+		}
+	}
+	return field.Name
+}
+
+func csvValue(v reflect.Value) string {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return ""
+		}
+		v = v.Elem()
+	}
+	return fmt.Sprint(v.Interface())
+}
@@ -0,0 +1,62 @@
+package engine
+
+import (
+	"encoding/csv"
+	"reflect"
+	"testing"
+)
+
+func TestCSVSliceRoundTrip(t *testing.T) {
+	type row struct {
+		ID      int    `csv:"id"`
+		Name    string `json:"name"`
+		Comment string
+		Secret  string `csv:"-"`
+	}
+	e := New()
+	e.GET("/export", func(c *Context) {
+		c.CSVSlice(200, []row{
+			{ID: 1, Name: "Smith, Ann", Comment: `said "hi"`, Secret: "x"},
+			{ID: 2, Name: "Bob", Comment: "line\nbreak"},
+		})
+	})
+
+	w := performRequest(e, "GET", "/export", nil)
+	if w.Code != 200 {
+		t.Fatalf("status = %d, want 200", w.Code)
+	}
+	if got := w.Header().Get("Content-Type"); got != "text/csv; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if got := w.Header().Get("Content-Disposition"); got != "attachment" {
+		t.Errorf("Content-Disposition = %q, want attachment", got)
+	}
+
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parsing the export: %v", err)
+	}
+	want := [][]string{
+		{"id", "name", "Comment"},
+		{"1", "Smith, Ann", `said "hi"`},
+		{"2", "Bob", "line\nbreak"},
+	}
+	if !reflect.DeepEqual(records, want) {
+		t.Errorf("records = %q, want %q", records, want)
+	}
+}
+
+func TestCSVKeepsContentDisposition(t *testing.T) {
+	e := New()
+	e.GET("/export", func(c *Context) {
+		c.Writer.Header().Set("Content-Disposition", `attachment; filename="users.csv"`)
+		c.CSV(200, [][]string{{"a", "b"}})
+	})
+	w := performRequest(e, "GET", "/export", nil)
+	if got := w.Header().Get("Content-Disposition"); got != `attachment; filename="users.csv"` {
+		t.Errorf("Content-Disposition = %q", got)
+	}
+	if got := w.Body.String(); got != "a,b\n" {
+		t.Errorf("body = %q, want \"a,b\\n\"", got)
+	}
+}
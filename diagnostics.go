@@ -0,0 +1,42 @@
+package engine
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync/atomic"
+)
+
+// a snapshot of the engine configuration and counters served by DiagnosticsHandler
+type diagnostics struct {
+	Debug            bool  `json:"debug"`
+	Routes           int   `json:"routes"`
+	GlobalMiddleware int   `json:"global_middleware"`
+	NotFoundHandlers int   `json:"not_found_handlers"`
+	Interceptors     int   `json:"interceptors"`
+	InFlight         int64 `json:"in_flight"`
+	TotalRequests    int64 `json:"total_requests"`
+}
+
+// DiagnosticsHandler returns a handler serving a JSON snapshot of the engine configuration
+// and runtime counters. It only answers in Debug mode, it returns a 404 otherwise:
+//
+//	r.GET("/debug/engine", engine.WrapH(r.DiagnosticsHandler()))
+func (engine *Engine) DiagnosticsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !engine.Debug {
+			http.NotFound(w, req)
+			return
+		}
+		snapshot := diagnostics{
+			Debug:            engine.Debug,
+			Routes:           engine.routes,
+			GlobalMiddleware: len(engine.Handlers),
+			NotFoundHandlers: len(engine.handlers404),
+			Interceptors:     len(engine.interceptors),
+			InFlight:         atomic.LoadInt64(&engine.inFlight),
+			TotalRequests:    atomic.LoadInt64(&engine.totalRequests),
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(snapshot)
+	})
+}
@@ -0,0 +1,33 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestDiagnosticsHandler(t *testing.T) {
+	e := New()
+	e.Use(func(c *Context) { c.Next() })
+	e.GET("/debug/engine", WrapH(e.DiagnosticsHandler()))
+	e.GET("/ping", func(c *Context) {})
+
+	if w := performRequest(e, "GET", "/debug/engine", nil); w.Code != 404 {
+		t.Errorf("without Debug: status = %d, want 404", w.Code)
+	}
+
+	e.Debug = true
+	performRequest(e, "GET", "/ping", nil)
+	w := performRequest(e, "GET", "/debug/engine", nil)
+	if w.Code != 200 || w.Header().Get("Content-Type") != "application/json" {
+		t.Fatalf("got %d with Content-Type %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	var got diagnostics
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	// the diagnostics request itself is in flight and counted
+	want := diagnostics{Debug: true, Routes: 2, GlobalMiddleware: 1, InFlight: 1, TotalRequests: 3}
+	if got != want {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
@@ -2,18 +2,36 @@ package engine
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"github.com/julienschmidt/httprouter"
 	"html/template"
+	"io/ioutil"
 	"math"
+	"net"
 	"net/http"
+	"net/url"
 	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+var ErrDrainTimeout = errors.New("engine: requests still in flight after drain timeout")
+
 const (
 	AbortIndex = math.MaxInt8 / 2
+
+	// the key under which StrictJSON enables the strict JSON decoding
+	strictJSONKey = "strictJSON"
+
+	// the key under which the methods allowed for a path are stored when handling a 405
+	AllowedMethodsKey = "allowedMethods"
 )
 
 type (
@@ -25,6 +43,7 @@ type (
 	ErrorMsg struct {
 		Err  string      `json:"error"`
 		Meta interface{} `json:"meta"`
+		err  error
 	}
 
 	ErrorMsgs []ErrorMsg
@@ -33,15 +52,29 @@ type (
 	// manage the flow, validate the JSON of a request and render a JSON response for example.
 	Context struct {
 		Req      *http.Request
-		Writer   http.ResponseWriter
+		Writer   ResponseWriter
 		Keys     map[string]interface{}
 		Errors   ErrorMsgs
 		Params   httprouter.Params
+		writer   responseWriter
+		status   *statusTextWriter
+		buffer   *bufferWriter
+		rawBody  []byte
+		query    url.Values
+		reqBody  *countingBody
+		done     []func(status, size int)
 		handlers []HandlerFunc
 		engine   *Engine
+		fullPath string
 		index    int8
 	}
 
+	// the NotFound handlers of a group
+	groupFallback struct {
+		prefix   string
+		handlers []HandlerFunc
+	}
+
 	// used internally to configure router, a RouterGroup  is associated with a prefix
 	// and an array of handlers(middleware)
 	RouterGroup struct {
@@ -53,11 +86,66 @@ type (
 
 	// Represents the web framework, it wrappers the blazing fast httprouter multiplexer and a list of global middleware
 	Engine struct {
+		// accessed atomically, kept first for 64-bit alignment on 32-bit platforms
+		inFlight      int64
+		totalRequests int64
+
 		*RouterGroup
+
+		// Debug enables the development helpers, e.g. DiagnosticsHandler()
+		Debug bool
+
+		// DefaultBindContentType is the format assumed by Bind when the request has no Content-Type,
+		// application/json by default
+		DefaultBindContentType string
+
+		// DisallowUnknownFields makes ParseBody reject the JSON objects with unknown fields, see StrictJSON()
+		DisallowUnknownFields bool
+
+		// JSONFieldNaming names the struct fields without a json tag in Context.JSON, e.g. SnakeCase
+		JSONFieldNaming FieldNaming
+
+		// StrictFormIndices makes the form binders reject the indexed keys with gaps, e.g. tags[0] and tags[2]
+		// or items[0][name] and items[2][name], instead of leaving the missing items to their zero value
+		StrictFormIndices bool
+
+		// ErrorBodyLimit caps the copy of the raw body attached to the error recorded by EnsureBody
+		// when binding fails, DefaultErrorBodyLimit by default. Set it to 0 to keep the bodies out of the errors.
+		ErrorBodyLimit int
+
+		// MaxMultipartMemory is the memory used to parse the multipart forms, DefaultMaxMultipartMemory by default.
+		// The files past it are stored in temporary files.
+		MaxMultipartMemory int64
+
 		handlers404   []HandlerFunc
+		handlers405   []HandlerFunc
 		router        *httprouter.Router
 		HTMLTemplates *template.Template
+
+		slowMu       sync.Mutex
+		slowRequests *slowRing
+
+		// idle is closed when the requests in flight drop to zero, while Drain waits
+		idleMu sync.Mutex
+		idle   chan struct{}
+
+		interceptors []ResponseInterceptor
+		sanitizer    Sanitizer
+		routes       int
+		namedRoutes  map[string]string
+		cookieSecret []byte
+		bodyRedactor func([]byte) []byte
+		errorMap     []mappedError
+		errorFuncs   []func(error) (int, interface{})
+		tasks        []periodicTask
+		fallbacks    []groupFallback
+		enums        map[reflect.Type][]string
+		decoders     fieldDecoders
+		wrapper      func(*Context, interface{}) interface{}
 	}
+
+	// ResponseInterceptor transforms the response body before it is sent, see Engine.AddResponseInterceptor
+	ResponseInterceptor = func(c *Context, body []byte) []byte
 )
 
 func (e ErrorMsgs) String() string {
@@ -73,10 +161,12 @@ func (e ErrorMsgs) String() string {
 // Return a new Blank Engine without any middleware attached
 // the most basic configuration
 func New() *Engine {
-	engine := &Engine{}
+	engine := &Engine{DefaultBindContentType: MIMEJSON, ErrorBodyLimit: DefaultErrorBodyLimit, MaxMultipartMemory: DefaultMaxMultipartMemory}
 	engine.RouterGroup = &RouterGroup{nil, "/", nil, engine}
 	engine.router = httprouter.New()
 	engine.router.NotFound = http.HandlerFunc(engine.handle404)
+	engine.router.MethodNotAllowed = http.HandlerFunc(engine.handle405)
+	engine.router.PanicHandler = engine.handlePanic
 	return engine
 }
 
@@ -91,15 +181,69 @@ func (engine *Engine) LoadHTMLTemplate(pattern string) {
 	engine.HTMLTemplates = template.Must(template.ParseGlob(pattern))
 }
 
+// Returns the underlying httprouter.Router, an escape hatch to tweak settings not exposed by the Engine.
+// Beware that the engine owns the NotFound, MethodNotAllowed and PanicHandler handlers, changing them
+// bypasses NotFound404(), NotAllowed405() and the panic logging, and that later engine calls may overwrite what you set.
+func (engine *Engine) Router() *httprouter.Router {
+	return engine.router
+}
+
+// AddResponseInterceptor adds a function transforming the body of the route responses before they are sent.
+// Once an interceptor is added, the responses are buffered in memory until the handlers return,
+// then the interceptors run in the order they were added.
+func (engine *Engine) AddResponseInterceptor(interceptor ResponseInterceptor) {
+	engine.interceptors = append(engine.interceptors, interceptor)
+}
+
 // Add handlers for NotFound, It return 404 code by default
 func (engine *Engine) NotFound404(handlers ...HandlerFunc) {
 	engine.handlers404 = handlers
 }
 
+// NotFound registers the handlers answering the unmatched paths under the prefix of the group,
+// instead of the NotFound404 handlers, e.g. to serve the index.html of a single-page app.
+// They run after the middleware of the group and write the status themselves.
+// When several groups match, the one with the longest prefix wins.
+func (group *RouterGroup) NotFound(handlers ...HandlerFunc) {
+	group.engine.fallbacks = append(group.engine.fallbacks, groupFallback{
+		prefix:   group.prefix,
+		handlers: group.allHandlers(handlers),
+	})
+}
+
+// fallback returns the NotFound handlers of the group with the longest prefix matching p, or nil
+func (engine *Engine) fallback(p string) []HandlerFunc {
+	var best *groupFallback
+	for i, f := range engine.fallbacks {
+		if p != f.prefix && !strings.HasPrefix(p, strings.TrimSuffix(f.prefix, "/")+"/") {
+			continue
+		}
+		if best == nil || len(f.prefix) > len(best.prefix) {
+			best = &engine.fallbacks[i]
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	return best.handlers
+}
+
 func (engine *Engine) handle404(w http.ResponseWriter, req *http.Request) {
+	if handlers := engine.fallback(req.URL.Path); handlers != nil {
+		// served like a route, with the interceptors and the OnResponseDone callbacks
+		c := engine.createContext(w, req, nil, handlers)
+		defer c.catchPanic()
+		c.handle()
+		return
+	}
+	engine.notFound(w, req)
+}
 
+// notFound runs the NotFound404 handlers, ignoring the group fallbacks
+func (engine *Engine) notFound(w http.ResponseWriter, req *http.Request) {
 	handlers := engine.allHandlers(engine.handlers404)
 	c := engine.createContext(w, req, nil, handlers)
+	defer c.catchPanic()
 	if engine.handlers404 == nil {
 		http.NotFound(c.Writer, c.Req)
 	} else {
@@ -107,15 +251,192 @@ func (engine *Engine) handle404(w http.ResponseWriter, req *http.Request) {
 	}
 
 	c.Next()
+	c.responseDone()
+}
+
+// notFound answers the request with a 404 from inside the route running, e.g. for a missing static file:
+// the NotFound404 handlers run on the current context, the middleware that already ran and the group fallbacks aren't involved.
+func (c *Context) notFound() {
+	if c.engine.handlers404 == nil {
+		http.NotFound(c.Writer, c.Req)
+		return
+	}
+	c.Writer.WriteHeader(404)
+	handlers, index := c.handlers, c.index
+	c.handlers, c.index = c.engine.handlers404, -1
+	c.Next()
+	c.handlers, c.index = handlers, index
+}
+
+// Add handlers for MethodNotAllowed, It return 405 code by default.
+// The handlers can read the methods registered for the path with Context.AllowedMethods(),
+// the Allow header is already set when they run.
+func (engine *Engine) NotAllowed405(handlers ...HandlerFunc) {
+	engine.handlers405 = handlers
+}
+
+func (engine *Engine) handle405(w http.ResponseWriter, req *http.Request) {
+	handlers := engine.allHandlers(engine.handlers405)
+	c := engine.createContext(w, req, nil, handlers)
+	defer c.catchPanic()
+	if allow := w.Header().Get("Allow"); allow != "" {
+		c.Set(AllowedMethodsKey, strings.Split(allow, ", "))
+	}
+	if engine.handlers405 == nil {
+		http.Error(c.Writer, http.StatusText(405), 405)
+	} else {
+		c.Writer.WriteHeader(405)
+	}
+
+	c.Next()
+	c.responseDone()
 }
 
 // ServeHttp makes the router implement the http.Handler interface
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	atomic.AddInt64(&engine.totalRequests, 1)
+	atomic.AddInt64(&engine.inFlight, 1)
+	defer engine.endRequest()
 	engine.router.ServeHTTP(w, req)
 }
 
-func (engine *Engine) Run(addr string) {
-	http.ListenAndServe(addr, engine)
+// endRequest counts a request out of flight, waking up Drain after the last one
+func (engine *Engine) endRequest() {
+	if atomic.AddInt64(&engine.inFlight, -1) != 0 {
+		return
+	}
+	engine.idleMu.Lock()
+	if engine.idle != nil {
+		close(engine.idle)
+		engine.idle = nil
+	}
+	engine.idleMu.Unlock()
+}
+
+// StripPrefix returns a handler serving the engine under prefix, e.g. when mounted at /api
+// behind another mux, the routes are registered without the prefix. Requests outside the prefix get a 404.
+// The prefix matches whole path segments: /api/users and /api, served as /, are in it, /apix isn't.
+func (engine *Engine) StripPrefix(prefix string) http.Handler {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		p, ok := stripSegments(req.URL.Path, prefix)
+		if !ok {
+			http.NotFound(w, req)
+			return
+		}
+		r := new(http.Request)
+		*r = *req
+		r.URL = new(url.URL)
+		*r.URL = *req.URL
+		r.URL.Path = p
+		r.URL.RawPath = ""
+		if req.URL.RawPath != "" {
+			r.URL.RawPath, _ = stripSegments(req.URL.RawPath, prefix)
+		}
+		engine.ServeHTTP(w, r)
+	})
+}
+
+// stripSegments removes prefix from path when it is made of the first segments of path
+func stripSegments(path, prefix string) (string, bool) {
+	if path == prefix {
+		return "/", true
+	}
+	if strings.HasPrefix(path, prefix+"/") {
+		return path[len(prefix):], true
+	}
+	return "", false
+}
+
+// Returns the number of requests currently being served.
+func (engine *Engine) InFlight() int {
+	return int(atomic.LoadInt64(&engine.inFlight))
+}
+
+// Drain waits until no request is being served anymore, at most timeout.
+// It returns ErrDrainTimeout if requests are still in flight after timeout.
+// Stop accepting new connections first, e.g. with http.Server.Shutdown.
+func (engine *Engine) Drain(timeout time.Duration) error {
+	engine.idleMu.Lock()
+	if engine.InFlight() == 0 {
+		engine.idleMu.Unlock()
+		return nil
+	}
+	if engine.idle == nil {
+		engine.idle = make(chan struct{})
+	}
+	idle := engine.idle
+	engine.idleMu.Unlock()
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	select {
+	case <-idle:
+		return nil
+	case <-deadline.C:
+		return ErrDrainTimeout
+	}
+}
+
+// Server returns a http.Server serving the engine on addr, the timeouts and the other settings
+// can be set before calling its ListenAndServe method.
+func (engine *Engine) Server(addr string) *http.Server {
+	return &http.Server{Addr: addr, Handler: engine}
+}
+
+// Run serves the engine on addr, it only returns on error.
+func (engine *Engine) Run(addr string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	engine.startTasks(ctx)
+	return http.ListenAndServe(addr, engine)
+}
+
+// RunTLS serves the engine on addr over HTTPS, it only returns on error.
+func (engine *Engine) RunTLS(addr, certFile, keyFile string) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	engine.startTasks(ctx)
+	return http.ListenAndServeTLS(addr, certFile, keyFile, engine)
+}
+
+// RunWithContext serves the engine on addr until ctx is done, the server is then shut down gracefully:
+// it stops accepting connections and waits for the requests in flight to complete.
+// It returns nil after a graceful shutdown, the error of the server otherwise.
+func (engine *Engine) RunWithContext(ctx context.Context, addr string) error {
+	if addr == "" {
+		addr = ":http"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return engine.serveWithContext(ctx, engine.Server(addr), listener)
+}
+
+func (engine *Engine) serveWithContext(ctx context.Context, server *http.Server, listener net.Listener) error {
+	taskCtx, cancel := context.WithCancel(ctx)
+	wait := engine.startTasks(taskCtx)
+	defer func() {
+		cancel()
+		wait()
+	}()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.Serve(listener)
+	}()
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	if err := server.Shutdown(context.Background()); err != nil {
+		return err
+	}
+	if err := <-errs; err != http.ErrServerClosed {
+		return err
+	}
+	return nil
 }
 
 /************************************/
@@ -123,14 +444,20 @@ func (engine *Engine) Run(addr string) {
 /************************************/
 
 func (group *RouterGroup) createContext(w http.ResponseWriter, req *http.Request, params httprouter.Params, handlers []HandlerFunc) *Context {
-	return &Context{
-		Writer:   w,
+	c := &Context{
 		Req:      req,
 		index:    -1,
 		engine:   group.engine,
 		Params:   params,
 		handlers: handlers,
 	}
+	c.writer.reset(w)
+	c.Writer = &c.writer
+	if req.Body != nil && req.Body != http.NoBody {
+		c.reqBody = &countingBody{ReadCloser: req.Body}
+		req.Body = c.reqBody
+	}
+	return c
 }
 
 // Adds middleware to the group
@@ -150,6 +477,14 @@ func (group *RouterGroup) Group(component string, handlers ...HandlerFunc) *Rout
 	}
 }
 
+// Version creates the group of the API version v at /v{v}, e.g. "/v1" for "1",
+// and calls register with it to add the routes of that version.
+func (engine *Engine) Version(v string, register func(*RouterGroup), handlers ...HandlerFunc) *RouterGroup {
+	group := engine.Group("/v"+strings.TrimPrefix(v, "v"), handlers...)
+	register(group)
+	return group
+}
+
 // Handle registers a new request handler and middleware with the given path and method.
 // The laster handler should be the real handler, the other ones should be middleware that can and should be shared among different routes.
 //
@@ -159,10 +494,24 @@ func (group *RouterGroup) Group(component string, handlers ...HandlerFunc) *Rout
 // frequently used, non-standardized or custom methods (e.g. for internal
 // communication with a proxy).
 func (group *RouterGroup) Handle(method, p string, handlers []HandlerFunc) {
-	p = path.Join(group.prefix, p)
+	group.handle(method, path.Join(group.prefix, p), handlers)
+}
+
+// HandleExact is like Handle but p is the full path of the route, registered verbatim:
+// the group prefix isn't prepended and the path isn't cleaned, so trailing slashes and double slashes are kept.
+// The group middleware still run.
+func (group *RouterGroup) HandleExact(method, p string, handlers ...HandlerFunc) {
+	group.handle(method, p, handlers)
+}
+
+func (group *RouterGroup) handle(method, p string, handlers []HandlerFunc) {
 	handlers = group.allHandlers(handlers)
+	group.engine.routes++
 	group.engine.router.Handle(method, p, func(w http.ResponseWriter, r *http.Request, params httprouter.Params) {
-		group.createContext(w, r, params, handlers).Next()
+		c := group.createContext(w, r, params, handlers)
+		c.fullPath = p
+		defer c.catchPanic()
+		c.handle()
 	})
 }
 
@@ -191,8 +540,12 @@ func (group *RouterGroup) PUT(path string, handlers ...HandlerFunc) {
 	group.Handle("PUT", path, handlers)
 }
 
+// allHandlers returns the middleware of the group and its parents followed by handlers, in a new slice:
+// appending to group.Handlers would write into its spare capacity, shared by every route of the group.
 func (group *RouterGroup) allHandlers(handlers []HandlerFunc) []HandlerFunc {
-	local := append(group.Handlers, handlers...)
+	local := make([]HandlerFunc, 0, len(group.Handlers)+len(handlers))
+	local = append(local, group.Handlers...)
+	local = append(local, handlers...)
 	if group.parent != nil {
 		return group.parent.allHandlers(local)
 	} else {
@@ -204,6 +557,68 @@ func (group *RouterGroup) allHandlers(handlers []HandlerFunc) []HandlerFunc {
 /****** FLOW AND ERROR MANAGEMENT****/
 /************************************/
 
+// handle runs the handlers of a route, buffering the response when there are interceptors
+func (c *Context) handle() {
+	interceptors := c.engine.interceptors
+	if len(interceptors) == 0 {
+		c.Next()
+		c.writeStatusText()
+		c.responseDone()
+		return
+	}
+	w := newBufferWriter(c.Writer)
+	c.Writer = w
+	c.buffer = w
+	defer func() {
+		c.Writer = w.ResponseWriter
+		c.buffer = nil
+	}()
+	c.Next()
+	c.writeStatusText()
+	if w.streaming {
+		// already sent as it was written
+		c.responseDone()
+		return
+	}
+
+	response := w.response()
+	for _, interceptor := range interceptors {
+		response.body = interceptor(c, response.body)
+	}
+	response.header.Del("Content-Length")
+	response.writeTo(w.ResponseWriter)
+	c.responseDone()
+}
+
+// SetStreaming(true) makes the rest of the response bypass the buffering of the response interceptors,
+// what was buffered so far is sent and the next writes go straight to the client, so they can be
+// flushed incrementally, e.g. for server-sent events or large downloads. The interceptors don't see
+// streamed responses. Once streaming, the response can't be buffered again and SetStreaming(false) does nothing.
+func (c *Context) SetStreaming(streaming bool) {
+	if !streaming {
+		return
+	}
+	// the inner buffers are flushed into the outer ones first
+	for b := c.buffer; b != nil; b = b.outer {
+		b.stream()
+	}
+}
+
+// OnResponseDone registers fn to be called once the route handlers returned and the response was
+// completely written, with the final status and number of body bytes sent.
+// The callbacks run in the order they were registered, exactly once per request.
+func (c *Context) OnResponseDone(fn func(status, size int)) {
+	c.done = append(c.done, fn)
+}
+
+func (c *Context) responseDone() {
+	done := c.done
+	c.done = nil
+	for _, fn := range done {
+		fn(c.writer.Status(), c.writer.Size())
+	}
+}
+
 // Next should be used only in the middleware.
 // It executes the pending handlers in the chain inside the calling handler.
 func (c *Context) Next() {
@@ -214,6 +629,11 @@ func (c *Context) Next() {
 	}
 }
 
+// responseStarted reports whether a status or a body was already written for the request
+func (c *Context) responseStarted() bool {
+	return c.Writer.Status() != 0
+}
+
 // Forces the system to do not continue calling the pending handlers.
 // For example, the first handler checks if the request is authorized. If it's not , context.Abort(401) shold be called.
 // The rest of pending handlers would never be called for that request.
@@ -222,6 +642,14 @@ func (c *Context) Abort(code int) {
 	c.index = AbortIndex
 }
 
+// StopHandlers skips the pending handlers without writing anything, the current handler is
+// expected to write the response itself.
+// Like with Abort, the middleware already entered still finish: the code they run after c.Next()
+// (logging, metrics...) is executed as the call stack unwinds. Unlike Abort, no status is written.
+func (c *Context) StopHandlers() {
+	c.index = AbortIndex
+}
+
 // Fail is the same than Abort plus an error message.
 // Calling `context.Fail(500, err)` is equivalent to:
 // ```
@@ -240,6 +668,7 @@ func (c *Context) Error(err error, meta interface{}) {
 	c.Errors = append(c.Errors, ErrorMsg{
 		Err:  err.Error(),
 		Meta: meta,
+		err:  err,
 	})
 }
 
@@ -247,6 +676,21 @@ func (c *Context) Error(err error, meta interface{}) {
 /******** METADATA MANAGEMENT********/
 /************************************/
 
+// Returns a copy of the current context that can be safely used outside the request's scope,
+// e.g. in a goroutine. The copy has its own Keys and no pending handlers, it must not write the response.
+func (c *Context) Copy() *Context {
+	cp := *c
+	cp.Writer = &cp.writer
+	cp.handlers = nil
+	cp.index = AbortIndex
+	cp.Keys = make(map[string]interface{}, len(c.Keys))
+	for k, v := range c.Keys {
+		cp.Keys[k] = v
+	}
+	cp.Errors = append(ErrorMsgs(nil), c.Errors...)
+	return &cp
+}
+
 // Sets a new pair key/value just for the specified context
 // It also lazy initializes the hashmap
 func (c *Context) Set(key string, value interface{}) {
@@ -273,50 +717,232 @@ func (c *Context) Get(key string) interface{} {
 	return item
 }
 
+// Returns the value stored under key, computing it and storing it in Keys on the first call,
+// so the derived values shared by several handlers (parsed auth, decoded claims) are computed once per request.
+func (c *Context) Once(key string, compute func() interface{}) interface{} {
+	if value, ok := c.Keys[key]; ok {
+		return value
+	}
+	value := compute()
+	c.Set(key, value)
+	return value
+}
+
+// Returns the methods registered for the requested path when handling a 405, nil otherwise.
+func (c *Context) AllowedMethods() []string {
+	methods, _ := c.Keys[AllowedMethodsKey].([]string)
+	return methods
+}
+
+// Returns the registered path of the matched route, e.g. "/user/:id".
+// It returns an empty string for the NotFound and MethodNotAllowed handlers.
+func (c *Context) FullPath() string {
+	return c.fullPath
+}
+
+// Returns the value of the catch-all parameter of the route, e.g. "/a/b.txt" for "/files/a/b.txt"
+// matching "/files/*path". The value starts with a slash, as httprouter reports it.
+// It returns an empty string when the route has no catch-all parameter.
+func (c *Context) CatchAll() string {
+	i := strings.LastIndex(c.fullPath, "*")
+	if i < 0 {
+		return ""
+	}
+	return c.Params.ByName(c.fullPath[i+1:])
+}
+
+/************************************/
+/************ INPUT DATA ************/
+/************************************/
+
+// Returns the keyed url query value if it exists, otherwise it returns an empty string.
+func (c *Context) Query(key string) string {
+	value, _ := c.GetQuery(key)
+	return value
+}
+
+// Like Query() but it returns fallback when the key is missing. `?x=` still returns an empty string.
+func (c *Context) DefaultQuery(key, fallback string) string {
+	if value, ok := c.GetQuery(key); ok {
+		return value
+	}
+	return fallback
+}
+
+// Like Query() but it also reports whether the key is present.
+// `?x=` returns ("", true) while a missing x returns ("", false).
+// The query is parsed once per request.
+func (c *Context) GetQuery(key string) (string, bool) {
+	if c.query == nil {
+		c.query = c.Req.URL.Query()
+	}
+	if values, ok := c.query[key]; ok && len(values) > 0 {
+		return values[0], true
+	}
+	return "", false
+}
+
+// Returns the keyed value from a POST urlencoded form or multipart form if it exists, otherwise it returns an empty string.
+func (c *Context) PostForm(key string) string {
+	value, _ := c.GetPostForm(key)
+	return value
+}
+
+// Like PostForm() but it also reports whether the key is present.
+// The body is parsed on the first call only. A body can be read once: the form is empty when ParseBody
+// or a binder consumed the body before, and ParseBody fails on the empty body once the form was parsed.
+func (c *Context) GetPostForm(key string) (string, bool) {
+	if c.Req.PostForm == nil {
+		c.Req.ParseMultipartForm(c.maxMultipartMemory())
+	}
+	if values, ok := c.Req.PostForm[key]; ok && len(values) > 0 {
+		return values[0], true
+	}
+	return "", false
+}
+
 /************************************/
 /******** ENCODING MANAGEMENT********/
 /************************************/
 
+// StrictJSON returns a middleware making ParseBody reject the JSON objects with fields unknown to the target struct,
+// EnsureBody then answers with a 400. Use Engine.DisallowUnknownFields to enable it everywhere.
+func StrictJSON() HandlerFunc {
+	return func(c *Context) {
+		c.Set(strictJSONKey, true)
+		c.Next()
+	}
+}
+
 // Like ParseBody() but this method also writes a 400 error if the json is not valid.
+// A body over the MaxBodySize limit is answered with a 413 instead.
+// The recorded error carries a copy of the raw body in its Meta, see Engine.ErrorBodyLimit.
 func (c *Context) EnsureBody(item interface{}) bool {
 	if err := c.ParseBody(item); err != nil {
-		c.Fail(400, err)
+		if isBodyTooLarge(err) {
+			c.bodyTooLarge(err)
+		} else {
+			c.failBinding(err)
+		}
 		return false
 	}
 	return true
 }
 
 // Parses the body content as a JSON input. It decodes the json payload into the struct specified as a pointer.
+//
+// Pointer fields let PATCH-like handlers know what the client sent:
+// a pointer is left nil when its key is absent, it points to the zero value when the key is explicitly null
+// and it points to the decoded value otherwise.
 func (c *Context) ParseBody(item interface{}) error {
-	decoder := json.NewDecoder(c.Req.Body)
-	if err := decoder.Decode(&item); err != nil {
-		return Validate(c, item)
-	} else {
+	if err := c.Req.Context().Err(); err != nil {
 		return err
 	}
+	body, err := ioutil.ReadAll(c.Req.Body)
+	if err != nil {
+		return err
+	}
+	c.rawBody = body
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if c.strictJSON() {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(item); err != nil {
+		return err
+	}
+	markNullPointers(reflect.ValueOf(item), body)
+	return finishBinding(c, item)
+}
+
+// strictJSON reports whether unknown JSON fields must be rejected, see StrictJSON()
+func (c *Context) strictJSON() bool {
+	if c.engine != nil && c.engine.DisallowUnknownFields {
+		return true
+	}
+	strict, _ := c.Keys[strictJSONKey].(bool)
+	return strict
+}
+
+// requestDone reports whether the request context is already cancelled or past its deadline.
+// In that case the error is recorded, the pending handlers are skipped and nothing must be written.
+func (c *Context) requestDone() bool {
+	err := c.Req.Context().Err()
+	if err == nil {
+		return false
+	}
+	c.Error(err, "request context done before rendering")
+	c.index = AbortIndex
+	return true
+}
+
+// setContentType sets the default Content-Type of a renderer, unless the handler already set one
+func (c *Context) setContentType(value string) {
+	header := c.Writer.Header()
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", value)
+	}
+}
+
+// SetResponseWrapper registers a function wrapping every payload rendered by Context.JSON,
+// e.g. EnvelopeWrapper, so the handlers render bare payloads and the responses share the same envelope.
+// The error bodies rendered by the engine itself (mapped errors, 413...) aren't wrapped.
+func (engine *Engine) SetResponseWrapper(wrapper func(c *Context, payload interface{}) interface{}) {
+	engine.wrapper = wrapper
+}
+
+// EnvelopeWrapper is a response wrapper rendering {"data": payload, "error": null},
+// the error is the message of the first error recorded by the handlers if any.
+func EnvelopeWrapper(c *Context, payload interface{}) interface{} {
+	var err interface{}
+	if len(c.Errors) > 0 {
+		err = c.Errors[0].Err
+	}
+	return H{"data": payload, "error": err}
 }
 
 // Serializes the given struct as a JSON into the response body in a fast and efficient way.
-// It also sets the Content-Type as "application/json"
+// It also sets the Content-Type as "application/json", unless the handler already set one.
+// The payload is wrapped by the wrapper of the engine if any, see SetResponseWrapper.
 func (c *Context) JSON(code int, obj interface{}) {
-	c.Writer.Header().Set("Content-Type", "application/json")
+	if c.engine != nil && c.engine.wrapper != nil {
+		obj = c.engine.wrapper(c, obj)
+	}
+	c.renderJSON(code, obj)
+}
+
+// renderJSON is JSON without the response wrapper, for the bodies rendered by the engine itself
+func (c *Context) renderJSON(code int, obj interface{}) {
+	if c.requestDone() {
+		return
+	}
+	named, err := c.nameFields(obj)
+	if err != nil {
+		c.Error(err, obj)
+		http.Error(c.Writer, err.Error(), 500)
+		return
+	}
+	c.setContentType("application/json")
 	if code >= 0 {
 		c.Writer.WriteHeader(code)
 	}
 	encoder := json.NewEncoder(c.Writer)
-	if err := encoder.Encode(obj); err != nil {
+	if err := encoder.Encode(named); err != nil {
 		c.Error(err, obj)
 		http.Error(c.Writer, err.Error(), 500)
 	}
 }
 
-// Serializes the given struct as XML into the response body in a fast and efficient way
-// It also sets the Content-Type as "application/json"
+// Serializes the given struct as XML into the response body, after the <?xml ...?> header line.
+// It also sets the Content-Type as "application/xml", unless the handler already set one
 func (c *Context) XML(code int, obj interface{}) {
-	c.Writer.Header().Set("Content-Type", "application/xml")
+	if c.requestDone() {
+		return
+	}
+	c.setContentType("application/xml")
 	if code >= 0 {
 		c.Writer.WriteHeader(code)
 	}
+	c.Writer.Write([]byte(xml.Header))
 	encoder := xml.NewEncoder(c.Writer)
 	if err := encoder.Encode(obj); err != nil {
 		c.Error(err, obj)
@@ -325,30 +951,104 @@ func (c *Context) XML(code int, obj interface{}) {
 }
 
 // Renders the html template specified by his file name.
-// It also update the http code and set the Content-Type as "application/html"
+// It also update the http code and set the Content-Type as "application/html", unless the handler already set one.
+// The template is rendered in a buffer, so when it fails or panics the client gets a plain 500 and none of the partial output,
+// the error is recorded with c.Error.
 func (c *Context) HTML(code int, name string, data interface{}) {
-	c.Writer.Header().Set("Content-Type", "application/html")
-	if code >= 0 {
-		c.Writer.WriteHeader(code)
+	if c.requestDone() {
+		return
 	}
-	if err := c.engine.HTMLTemplates.ExecuteTemplate(c.Writer, name, data); err != nil {
+	body, err := c.executeTemplate(name, data)
+	if err != nil {
 		c.Error(err, map[string]interface{}{
 			"name": name,
 			"data": data,
 		})
-		http.Error(c.Writer, err.Error(), 500)
+		// the errors of the template funcs carry their panics, they are kept for the logs
+		http.Error(c.Writer, http.StatusText(500), 500)
+		return
 	}
+	c.setContentType("application/html")
+	if code >= 0 {
+		c.Writer.WriteHeader(code)
+	}
+	c.Writer.Write(body)
 }
 
-// Writes the given string into the response body and set the Content-Type to "application/plain"
+// Writes the given string into the response body and set the Content-Type to "application/plain",
+// unless the handler already set one
 func (c *Context) String(code int, msg string) {
-	c.Writer.Header().Set("Content-Type", "application/plain")
+	if c.requestDone() {
+		return
+	}
+	c.setContentType("application/plain")
 	c.Writer.WriteHeader(code)
 	c.Writer.Write([]byte(msg))
 }
 
-// Writes some data into the body stream and updates status code
+// Writes the status code. If the handlers don't write any body, the standard text of the status
+// is written as body once they return, e.g. "Not Found" for a 404, except for the statuses without a body like 204.
+func (c *Context) Status(code int) {
+	if c.requestDone() {
+		return
+	}
+	c.Writer.WriteHeader(code)
+	c.status = &statusTextWriter{ResponseWriter: c.Writer, code: code}
+	c.Writer = c.status
+}
+
+// writeStatusText writes the default body of Status() when nothing else was written.
+// The middleware buffering the response call it before recording the buffer.
+func (c *Context) writeStatusText() {
+	if c.status != nil && !c.status.wroteBody {
+		c.status.wroteBody = true
+		if bodyAllowed(c.status.code) {
+			c.status.ResponseWriter.Write([]byte(http.StatusText(c.status.code)))
+		}
+	}
+}
+
+// bodyAllowed reports whether a response with the status can have a body, unlike a 1xx, 204 or 304
+func bodyAllowed(code int) bool {
+	return code >= 200 && code != http.StatusNoContent && code != http.StatusNotModified
+}
+
+// Writes a 204 No Content status without any body nor Content-Type.
+func (c *Context) NoContent() {
+	if c.requestDone() {
+		return
+	}
+	c.Writer.Header().Del("Content-Type")
+	c.Writer.WriteHeader(http.StatusNoContent)
+}
+
+// Serves data from memory with the given Content-Type, honoring Range and If-Range headers.
+// It answers with a 206 Partial Content for satisfiable ranged requests and a 200 otherwise.
+func (c *Context) DataRange(contentType string, data []byte) {
+	if c.requestDone() {
+		return
+	}
+	c.Writer.Header().Set("Content-Type", contentType)
+	http.ServeContent(c.Writer, c.Req, "", time.Time{}, bytes.NewReader(data))
+}
+
+// Writes some data into the body stream and updates status code.
+// The Content-Type defaults to "application/octet-stream" so net/http doesn't sniff it from the data.
 func (c *Context) Data(code int, data []byte) {
+	c.DataWithType(code, "", data)
+}
+
+// Like Data() but sets the given Content-Type, an empty one keeps the Content-Type already set if any.
+func (c *Context) DataWithType(code int, contentType string, data []byte) {
+	if c.requestDone() {
+		return
+	}
+	header := c.Writer.Header()
+	if contentType != "" {
+		header.Set("Content-Type", contentType)
+	} else if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/octet-stream")
+	}
 	c.Writer.WriteHeader(code)
 	c.Writer.Write(data)
 }
@@ -0,0 +1,915 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// performRequest serves a request through h, headers are key, value pairs
+func performRequest(h http.Handler, method, path string, body io.Reader, headers ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, body)
+	for i := 0; i+1 < len(headers); i += 2 {
+		req.Header.Set(headers[i], headers[i+1])
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestGetQueryPresence(t *testing.T) {
+	tests := []struct {
+		url     string
+		value   string
+		present bool
+	}{
+		{"/?x=1", "1", true},
+		{"/?x=", "", true},
+		{"/?x", "", true},
+		{"/?y=1", "", false},
+		{"/", "", false},
+	}
+	for _, tt := range tests {
+		var value string
+		var present bool
+		e := New()
+		e.GET("/", func(c *Context) {
+			value, present = c.GetQuery("x")
+		})
+		performRequest(e, "GET", tt.url, nil)
+		if value != tt.value || present != tt.present {
+			t.Errorf("%s: got (%q, %v), want (%q, %v)", tt.url, value, present, tt.value, tt.present)
+		}
+	}
+}
+
+func TestGetPostFormPresence(t *testing.T) {
+	var empty, missing bool
+	var value string
+	e := New()
+	e.POST("/", func(c *Context) {
+		value, _ = c.GetPostForm("name")
+		_, empty = c.GetPostForm("empty")
+		_, missing = c.GetPostForm("missing")
+	})
+	performRequest(e, "POST", "/", strings.NewReader("name=bob&empty="), "Content-Type", MIMEPOSTForm)
+	if value != "bob" || !empty || missing {
+		t.Errorf("got name %q, empty present %v, missing present %v", value, empty, missing)
+	}
+}
+
+func TestNotAllowed405AllowHeader(t *testing.T) {
+	var allowed []string
+	e := New()
+	e.NotAllowed405(func(c *Context) {
+		allowed = c.AllowedMethods()
+		c.String(-1, "nope")
+	})
+	e.GET("/item", func(c *Context) {})
+	e.PUT("/item", func(c *Context) {})
+
+	w := performRequest(e, "DELETE", "/item", nil)
+	if w.Code != 405 || w.Body.String() != "nope" {
+		t.Fatalf("got %d %q, want the custom 405", w.Code, w.Body.String())
+	}
+	allow := w.Header().Get("Allow")
+	for _, method := range []string{"GET", "PUT"} {
+		if !strings.Contains(allow, method) {
+			t.Errorf("Allow header %q doesn't list %s", allow, method)
+		}
+	}
+	if strings.Join(allowed, ", ") != allow {
+		t.Errorf("AllowedMethods() = %v, want the methods of the Allow header %q", allowed, allow)
+	}
+}
+
+func TestRenderAfterCancelledContext(t *testing.T) {
+	var errs []ErrorMsg
+	var parseErr error
+	e := New()
+	e.POST("/", func(c *Context) {
+		var item struct{ Name string }
+		parseErr = c.ParseBody(&item)
+		c.JSON(200, H{"name": "bob"})
+		errs = c.Errors
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"bob"}`)).WithContext(ctx)
+	w := httptest.NewRecorder()
+	e.ServeHTTP(w, req)
+
+	if parseErr != context.Canceled {
+		t.Errorf("ParseBody returned %v, want context.Canceled", parseErr)
+	}
+	if len(errs) != 1 || errs[0].Err != context.Canceled.Error() {
+		t.Errorf("recorded errors %v, want the cancellation", errs)
+	}
+	if w.Body.Len() != 0 || w.Header().Get("Content-Type") != "" {
+		t.Errorf("the renderer wrote %q with the header %v", w.Body.String(), w.Header())
+	}
+}
+
+func TestDataRange(t *testing.T) {
+	e := New()
+	e.GET("/", func(c *Context) {
+		c.DataRange("text/plain", []byte("0123456789"))
+	})
+
+	w := performRequest(e, "GET", "/", nil, "Range", "bytes=2-5")
+	if w.Code != 206 || w.Body.String() != "2345" {
+		t.Errorf("ranged request: got %d %q, want 206 \"2345\"", w.Code, w.Body.String())
+	}
+	if cr := w.Header().Get("Content-Range"); cr != "bytes 2-5/10" {
+		t.Errorf("Content-Range = %q", cr)
+	}
+
+	w = performRequest(e, "GET", "/", nil)
+	if w.Code != 200 || w.Body.String() != "0123456789" || w.Header().Get("Accept-Ranges") != "bytes" {
+		t.Errorf("full request: got %d %q %v", w.Code, w.Body.String(), w.Header())
+	}
+
+	if w := performRequest(e, "GET", "/", nil, "Range", "bytes=20-30"); w.Code != 416 {
+		t.Errorf("unsatisfiable range: got %d, want 416", w.Code)
+	}
+}
+
+func TestDrainWaitsForInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	finished := make(chan struct{})
+	e := New()
+	e.GET("/slow", func(c *Context) {
+		close(started)
+		time.Sleep(30 * time.Millisecond)
+		close(finished)
+	})
+
+	go performRequest(e, "GET", "/slow", nil)
+	<-started
+	if n := e.InFlight(); n != 1 {
+		t.Errorf("InFlight() = %d, want 1", n)
+	}
+	if err := e.Drain(time.Second); err != nil {
+		t.Fatalf("Drain returned %v", err)
+	}
+	select {
+	case <-finished:
+	default:
+		t.Error("Drain returned before the slow request finished")
+	}
+	if n := e.InFlight(); n != 0 {
+		t.Errorf("InFlight() = %d after Drain, want 0", n)
+	}
+}
+
+func TestDrainTimeout(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	e := New()
+	e.GET("/slow", func(c *Context) {
+		close(started)
+		<-release
+	})
+
+	go performRequest(e, "GET", "/slow", nil)
+	<-started
+	defer close(release)
+	if err := e.Drain(10 * time.Millisecond); err != ErrDrainTimeout {
+		t.Errorf("Drain returned %v, want ErrDrainTimeout", err)
+	}
+}
+
+func TestNoContent(t *testing.T) {
+	var status, size int
+	e := New()
+	e.DELETE("/item", func(c *Context) {
+		c.Writer.Header().Set("Content-Type", MIMEJSON)
+		c.NoContent()
+		status, size = c.Writer.Status(), c.Writer.Size()
+	})
+
+	w := performRequest(e, "DELETE", "/item", nil)
+	if w.Code != 204 || w.Body.Len() != 0 {
+		t.Errorf("got %d with body %q, want an empty 204", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "" {
+		t.Errorf("Content-Type = %q, want none", ct)
+	}
+	if status != 204 || size != 0 {
+		t.Errorf("the writer tracked status %d and size %d", status, size)
+	}
+}
+
+func TestRouterEscapeHatch(t *testing.T) {
+	e := New()
+	e.GET("/path", func(c *Context) {
+		c.String(200, "ok")
+	})
+	e.Router().RedirectTrailingSlash = false
+
+	if w := performRequest(e, "GET", "/path/", nil); w.Code != 404 {
+		t.Errorf("status = %d, want 404 without the trailing slash redirect", w.Code)
+	}
+}
+
+func TestResponseInterceptor(t *testing.T) {
+	e := New()
+	e.AddResponseInterceptor(func(c *Context, body []byte) []byte {
+		return bytes.ToUpper(body)
+	})
+	e.AddResponseInterceptor(func(c *Context, body []byte) []byte {
+		return append(body, '!')
+	})
+	e.GET("/hello", func(c *Context) {
+		c.Writer.Header().Set("Content-Length", "5")
+		c.String(201, "hello")
+	})
+
+	w := performRequest(e, "GET", "/hello", nil)
+	if w.Code != 201 || w.Body.String() != "HELLO!" {
+		t.Errorf("got %d %q, want 201 HELLO!", w.Code, w.Body.String())
+	}
+	if cl := w.Header().Get("Content-Length"); cl != "" {
+		t.Errorf("the stale Content-Length %q was sent", cl)
+	}
+}
+
+func TestCatchAll(t *testing.T) {
+	var got string
+	e := New()
+	e.GET("/files/*path", func(c *Context) {
+		got = c.CatchAll()
+	})
+	e.GET("/users/:id", func(c *Context) {
+		got = c.CatchAll()
+	})
+
+	performRequest(e, "GET", "/files/docs/a.txt", nil)
+	if got != "/docs/a.txt" {
+		t.Errorf("CatchAll() = %q, want /docs/a.txt", got)
+	}
+	performRequest(e, "GET", "/users/1", nil)
+	if got != "" {
+		t.Errorf("CatchAll() without a catch-all = %q, want empty", got)
+	}
+}
+
+func TestStrictJSON(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+	handler := func(c *Context) {
+		var u user
+		if c.EnsureBody(&u) {
+			c.String(200, u.Name)
+		}
+	}
+	e := New()
+	e.POST("/strict", StrictJSON(), handler)
+	e.POST("/lenient", handler)
+
+	tests := []struct {
+		path, body string
+		code       int
+	}{
+		{"/strict", `{"name":"ann"}`, 200},
+		{"/strict", `{"name":"ann","admin":true}`, 400},
+		{"/lenient", `{"name":"ann","admin":true}`, 200},
+	}
+	for _, tt := range tests {
+		w := performRequest(e, "POST", tt.path, strings.NewReader(tt.body), "Content-Type", MIMEJSON)
+		if w.Code != tt.code {
+			t.Errorf("POST %s %s: status = %d, want %d", tt.path, tt.body, w.Code, tt.code)
+		}
+	}
+
+	e = New()
+	e.DisallowUnknownFields = true
+	e.POST("/lenient", handler)
+	w := performRequest(e, "POST", "/lenient", strings.NewReader(`{"admin":true}`), "Content-Type", MIMEJSON)
+	if w.Code != 400 {
+		t.Errorf("with DisallowUnknownFields: status = %d, want 400", w.Code)
+	}
+}
+
+func TestStatusDefaultsToStatusText(t *testing.T) {
+	e := New()
+	e.GET("/missing", func(c *Context) {
+		c.Status(404)
+	})
+	e.GET("/custom", func(c *Context) {
+		c.Status(404)
+		c.Writer.Write([]byte("no such user"))
+	})
+
+	w := performRequest(e, "GET", "/missing", nil)
+	if w.Code != 404 || w.Body.String() != "Not Found" {
+		t.Errorf("bare status: got %d %q, want 404 Not Found", w.Code, w.Body.String())
+	}
+	w = performRequest(e, "GET", "/custom", nil)
+	if w.Code != 404 || w.Body.String() != "no such user" {
+		t.Errorf("with a body: got %d %q, want the handler body only", w.Code, w.Body.String())
+	}
+	e.GET("/empty", func(c *Context) {
+		c.Status(204)
+	})
+	if w = performRequest(e, "GET", "/empty", nil); w.Code != 204 || w.Body.Len() != 0 {
+		t.Errorf("204: got %d %q, want no body", w.Code, w.Body.String())
+	}
+}
+
+func TestStatusTextUnderBufferingMiddleware(t *testing.T) {
+	accepted := func(c *Context) {
+		c.Status(202)
+	}
+	tests := []struct {
+		name    string
+		mw      HandlerFunc
+		headers []string
+	}{
+		{"cache", Cache(time.Minute, nil), nil},
+		{"single flight", SingleFlight(nil), nil},
+		{"idempotency", Idempotency(nil), []string{IdempotencyKeyHeader, "k1"}},
+	}
+	for _, tt := range tests {
+		e := New()
+		e.Use(tt.mw)
+		e.GET("/job", accepted)
+		e.POST("/job", accepted)
+		method := "GET"
+		if tt.headers != nil {
+			method = "POST"
+		}
+		// the second request is replayed
+		for i := 0; i < 2; i++ {
+			w := performRequest(e, method, "/job", nil, tt.headers...)
+			if w.Code != 202 || w.Body.String() != "Accepted" {
+				t.Errorf("%s, request %d: got %d %q, want 202 Accepted", tt.name, i+1, w.Code, w.Body.String())
+			}
+		}
+	}
+}
+
+func TestVersionGroups(t *testing.T) {
+	e := New()
+	e.Version("1", func(v1 *RouterGroup) {
+		v1.GET("/users", func(c *Context) {
+			c.String(200, "v1 users")
+		})
+	})
+	e.Version("v2", func(v2 *RouterGroup) {
+		v2.GET("/users", func(c *Context) {
+			c.String(200, "v2 users")
+		})
+	}, func(c *Context) {
+		c.Writer.Header().Set("X-API-Version", "2")
+		c.Next()
+	})
+
+	w := performRequest(e, "GET", "/v1/users", nil)
+	if w.Body.String() != "v1 users" || w.Header().Get("X-API-Version") != "" {
+		t.Errorf("/v1/users: got %q with X-API-Version %q", w.Body.String(), w.Header().Get("X-API-Version"))
+	}
+	w = performRequest(e, "GET", "/v2/users", nil)
+	if w.Body.String() != "v2 users" || w.Header().Get("X-API-Version") != "2" {
+		t.Errorf("/v2/users: got %q with X-API-Version %q", w.Body.String(), w.Header().Get("X-API-Version"))
+	}
+}
+
+func TestStopHandlers(t *testing.T) {
+	var logged []string
+	reached := false
+	e := New()
+	e.Use(func(c *Context) {
+		c.Next()
+		logged = append(logged, c.Req.URL.Path)
+	})
+	e.GET("/cached", func(c *Context) {
+		c.String(200, "from cache")
+		c.StopHandlers()
+	}, func(c *Context) {
+		reached = true
+	})
+
+	w := performRequest(e, "GET", "/cached", nil)
+	if reached {
+		t.Error("the pending handler ran after StopHandlers")
+	}
+	if w.Code != 200 || w.Body.String() != "from cache" {
+		t.Errorf("got %d %q", w.Code, w.Body.String())
+	}
+	if len(logged) != 1 || logged[0] != "/cached" {
+		t.Errorf("the logging middleware logged %v", logged)
+	}
+}
+
+func TestDataContentType(t *testing.T) {
+	e := New()
+	e.GET("/explicit", func(c *Context) {
+		c.DataWithType(200, "image/svg+xml", []byte("<svg/>"))
+	})
+	e.GET("/default", func(c *Context) {
+		c.Data(200, []byte("<html>"))
+	})
+	e.GET("/preset", func(c *Context) {
+		c.Writer.Header().Set("Content-Type", "text/csv")
+		c.Data(200, []byte("a,b"))
+	})
+
+	tests := []struct {
+		path, want string
+	}{
+		{"/explicit", "image/svg+xml"},
+		{"/default", "application/octet-stream"},
+		{"/preset", "text/csv"},
+	}
+	for _, tt := range tests {
+		w := performRequest(e, "GET", tt.path, nil)
+		if got := w.Header().Get("Content-Type"); got != tt.want {
+			t.Errorf("GET %s: Content-Type = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestStripPrefixUnderServeMux(t *testing.T) {
+	var path string
+	e := New()
+	e.GET("/users/:id", func(c *Context) {
+		path = c.Req.URL.Path
+		c.String(200, "user "+c.Params.ByName("id"))
+	})
+	mux := http.NewServeMux()
+	mux.Handle("/api/", e.StripPrefix("/api/"))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("site"))
+	})
+
+	w := performRequest(mux, "GET", "/api/users/7", nil)
+	if w.Code != 200 || w.Body.String() != "user 7" || path != "/users/7" {
+		t.Errorf("got %d %q, the engine routed %q", w.Code, w.Body.String(), path)
+	}
+	if w := performRequest(mux, "GET", "/about", nil); w.Body.String() != "site" {
+		t.Errorf("outside the prefix: got %q", w.Body.String())
+	}
+
+	// the prefix matches whole segments, without redirecting out of the mount
+	e.GET("/", func(c *Context) {
+		c.String(200, "index")
+	})
+	h := e.StripPrefix("/api")
+	if w := performRequest(h, "GET", "/api", nil); w.Code != 200 || w.Body.String() != "index" {
+		t.Errorf("/api: got %d %q, want 200 index", w.Code, w.Body.String())
+	}
+	for _, p := range []string{"/apix", "/apix/users/7", "/users/7"} {
+		if w := performRequest(h, "GET", p, nil); w.Code != 404 {
+			t.Errorf("%s: status = %d, want 404", p, w.Code)
+		}
+	}
+}
+
+func TestOnResponseDone(t *testing.T) {
+	var calls []string
+	var status, size int
+	e := New()
+	e.Use(func(c *Context) {
+		c.OnResponseDone(func(s, n int) {
+			calls = append(calls, "first")
+			status, size = s, n
+		})
+		c.Next()
+	})
+	e.GET("/chunks", func(c *Context) {
+		c.OnResponseDone(func(int, int) {
+			calls = append(calls, "second")
+		})
+		c.Writer.WriteHeader(201)
+		c.Writer.Write([]byte("hello "))
+		c.Writer.Write([]byte("world"))
+	})
+
+	performRequest(e, "GET", "/chunks", nil)
+	if status != 201 || size != 11 {
+		t.Errorf("the callback got status %d and size %d, want 201 and 11", status, size)
+	}
+	if len(calls) != 2 || calls[0] != "first" || calls[1] != "second" {
+		t.Errorf("calls = %v, want first then second once", calls)
+	}
+
+	// after the interceptors, with the size of the transformed body
+	e.AddResponseInterceptor(func(c *Context, body []byte) []byte {
+		return body[:5]
+	})
+	calls = nil
+	performRequest(e, "GET", "/chunks", nil)
+	if status != 201 || size != 5 || len(calls) != 2 {
+		t.Errorf("with an interceptor: status %d, size %d, calls %v", status, size, calls)
+	}
+}
+
+func TestOnResponseDoneOutsideRoutes(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var statuses []int
+	e := New()
+	e.Use(func(c *Context) {
+		c.OnResponseDone(func(status, size int) {
+			statuses = append(statuses, status)
+		})
+		c.Next()
+	})
+	e.GET("/panic", func(c *Context) {
+		panic("boom")
+	})
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{"GET", "/missing", 404},
+		{"POST", "/panic", 405},
+		{"GET", "/panic", 500},
+	}
+	for _, tt := range tests {
+		statuses = nil
+		performRequest(e, tt.method, tt.path, nil)
+		if len(statuses) != 1 || statuses[0] != tt.want {
+			t.Errorf("%s %s: the callbacks ran with %v, want once with %d", tt.method, tt.path, statuses, tt.want)
+		}
+	}
+}
+
+func TestSetStreamingBypassesBuffering(t *testing.T) {
+	w := httptest.NewRecorder()
+	var seen []string
+	e := New()
+	e.AddResponseInterceptor(func(c *Context, body []byte) []byte {
+		return append(body, " (intercepted)"...)
+	})
+	e.GET("/events", func(c *Context) {
+		c.SetStreaming(true)
+		for _, event := range []string{"a", "b"} {
+			c.Writer.Write([]byte("data: " + event + "\n\n"))
+			c.Writer.Flush()
+			seen = append(seen, w.Body.String())
+		}
+	})
+	e.GET("/json", func(c *Context) {
+		c.JSON(200, H{"ok": true})
+		seen = append(seen, w.Body.String())
+	})
+
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+	if len(seen) != 2 || seen[0] != "data: a\n\n" || seen[1] != "data: a\n\ndata: b\n\n" {
+		t.Errorf("the client received %q while streaming", seen)
+	}
+	if !w.Flushed || w.Body.String() != "data: a\n\ndata: b\n\n" {
+		t.Errorf("streamed body = %q, flushed %v", w.Body.String(), w.Flushed)
+	}
+
+	seen = nil
+	w = httptest.NewRecorder()
+	e.ServeHTTP(w, httptest.NewRequest("GET", "/json", nil))
+	if len(seen) != 1 || seen[0] != "" {
+		t.Errorf("the client received %q before the handler returned", seen)
+	}
+	if got := w.Body.String(); got != "{\"ok\":true}\n (intercepted)" {
+		t.Errorf("buffered body = %q", got)
+	}
+}
+
+func TestXMLRoundTrip(t *testing.T) {
+	type item struct {
+		XMLName xml.Name `xml:"item"`
+		ID      int      `xml:"id,attr"`
+		Name    string   `xml:"name"`
+	}
+	e := New()
+	e.GET("/item", func(c *Context) {
+		c.XML(200, item{ID: 7, Name: "ann & bob"})
+	})
+
+	w := performRequest(e, "GET", "/item", nil)
+	if ct := w.Header().Get("Content-Type"); ct != "application/xml" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.HasPrefix(w.Body.String(), xml.Header) {
+		t.Errorf("the body %q doesn't start with the xml header", w.Body.String())
+	}
+	var got item
+	if err := xml.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("the body %q isn't XML: %v", w.Body.String(), err)
+	}
+	if got.ID != 7 || got.Name != "ann & bob" {
+		t.Errorf("got %+v", got)
+	}
+}
+
+func TestQueryHelpers(t *testing.T) {
+	var query, fallback, empty, post string
+	e := New()
+	e.POST("/search", func(c *Context) {
+		query = c.Query("q")
+		fallback = c.DefaultQuery("page", "1")
+		empty = c.DefaultQuery("sort", "name")
+		post = c.PostForm("filter")
+	})
+	performRequest(e, "POST", "/search?q=go&sort=", strings.NewReader("filter=new"), "Content-Type", MIMEPOSTForm)
+	if query != "go" || fallback != "1" || empty != "" || post != "new" {
+		t.Errorf("got q %q, page %q, sort %q, filter %q", query, fallback, empty, post)
+	}
+}
+
+func TestPostFormAfterParseBody(t *testing.T) {
+	var form string
+	var present bool
+	e := New()
+	e.POST("/", func(c *Context) {
+		var item map[string]string
+		c.ParseBody(&item)
+		form, present = c.GetPostForm("name")
+	})
+	performRequest(e, "POST", "/", strings.NewReader(`{"name":"ann"}`), "Content-Type", MIMEPOSTForm)
+	if form != "" || present {
+		t.Errorf("the consumed body gave the form value (%q, %v)", form, present)
+	}
+}
+
+func TestHandleExact(t *testing.T) {
+	var fullPath string
+	e := New()
+	e.Router().RedirectTrailingSlash = false
+	e.Router().RedirectFixedPath = false
+	api := e.Group("/api", func(c *Context) {
+		c.Writer.Header().Set("X-Group", "api")
+		c.Next()
+	})
+	api.HandleExact("GET", "/legacy//export/", func(c *Context) {
+		fullPath = c.FullPath()
+		c.String(200, "exact")
+	})
+
+	w := performRequest(e, "GET", "/legacy//export/", nil)
+	if w.Code != 200 || w.Body.String() != "exact" || fullPath != "/legacy//export/" {
+		t.Errorf("got %d %q for the route %q", w.Code, w.Body.String(), fullPath)
+	}
+	if w.Header().Get("X-Group") != "api" {
+		t.Error("the group middleware didn't run")
+	}
+	for _, p := range []string{"/legacy/export", "/api/legacy//export/"} {
+		if w := performRequest(e, "GET", p, nil); w.Code != 404 {
+			t.Errorf("GET %s: status = %d, want 404", p, w.Code)
+		}
+	}
+}
+
+func TestAllHandlersDoesNotLeakBetweenRoutes(t *testing.T) {
+	var calls []string
+	mark := func(name string) HandlerFunc {
+		return func(c *Context) {
+			calls = append(calls, name)
+			c.Next()
+		}
+	}
+	e := New()
+	// the spare capacity of the group handlers used to be shared by its routes
+	e.Handlers = make([]HandlerFunc, 0, 8)
+	e.Use(mark("global"))
+	e.GET("/a", mark("a"), func(c *Context) {})
+	e.GET("/b", mark("b"), func(c *Context) {})
+	group := e.Group("/g", mark("group"))
+	group.GET("/c", mark("c"), func(c *Context) {})
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/a", "global a"},
+		{"/b", "global b"},
+		{"/g/c", "global group c"},
+	}
+	for _, tt := range tests {
+		calls = nil
+		performRequest(e, "GET", tt.path, nil)
+		if got := strings.Join(calls, " "); got != tt.want {
+			t.Errorf("GET %s ran %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestServeWithContextGracefulShutdown(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	e := New()
+	e.GET("/slow", func(c *Context) {
+		close(started)
+		<-release
+		c.String(200, "finished")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- e.serveWithContext(ctx, e.Server(listener.Addr().String()), listener)
+	}()
+
+	type result struct {
+		body string
+		err  error
+	}
+	responses := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			responses <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		responses <- result{string(body), err}
+	}()
+
+	<-started
+	cancel()
+	select {
+	case err := <-served:
+		t.Fatalf("the server returned %v with a request in flight", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+
+	if r := <-responses; r.err != nil || r.body != "finished" {
+		t.Errorf("the in-flight request got %q, %v", r.body, r.err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("graceful shutdown returned %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the server didn't shut down")
+	}
+	if _, err := net.Dial("tcp", listener.Addr().String()); err == nil {
+		t.Error("the server still accepts connections")
+	}
+}
+
+func TestGroupNotFound(t *testing.T) {
+	e := New()
+	e.NotFound404(func(c *Context) {
+		c.Writer.Write([]byte("global 404"))
+	})
+	e.AddResponseInterceptor(func(c *Context, body []byte) []byte {
+		return append(body, '!')
+	})
+	app := e.Group("/app", func(c *Context) {
+		c.Writer.Header().Set("X-App", "1")
+		c.Next()
+	})
+	app.GET("/login", func(c *Context) {
+		c.String(200, "login")
+	})
+	app.NotFound(func(c *Context) {
+		c.String(200, "index.html")
+	})
+	e.Group("/app/admin").NotFound(func(c *Context) {
+		c.String(404, "admin 404")
+	})
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/app/login", 200, "login!"},
+		{"/app/settings/profile", 200, "index.html!"},
+		{"/app", 200, "index.html!"},
+		// the longest prefix wins
+		{"/app/admin/users", 404, "admin 404!"},
+		{"/application", 404, "global 404"},
+		{"/other", 404, "global 404"},
+	}
+	for _, tt := range tests {
+		w := performRequest(e, "GET", tt.path, nil)
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("GET %s: got %d %q, want %d %q", tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+	if w := performRequest(e, "GET", "/app/settings", nil); w.Header().Get("X-App") != "1" {
+		t.Error("the group middleware didn't run before the fallback")
+	}
+}
+
+func TestRenderersKeepPresetContentType(t *testing.T) {
+	e := New()
+	e.GET("/json", func(c *Context) {
+		c.Writer.Header().Set("Content-Type", "application/vnd.api+json")
+		c.JSON(200, H{"ok": true})
+	})
+	e.GET("/xml", func(c *Context) {
+		c.Writer.Header().Set("Content-Type", "application/atom+xml")
+		c.XML(200, struct {
+			XMLName xml.Name `xml:"feed"`
+		}{})
+	})
+	e.GET("/string", func(c *Context) {
+		c.Writer.Header().Set("Content-Type", "text/markdown")
+		c.String(200, "# title")
+	})
+	e.GET("/default", func(c *Context) {
+		c.JSON(200, H{"ok": true})
+	})
+
+	tests := []struct {
+		path, want string
+	}{
+		{"/json", "application/vnd.api+json"},
+		{"/xml", "application/atom+xml"},
+		{"/string", "text/markdown"},
+		{"/default", "application/json"},
+	}
+	for _, tt := range tests {
+		w := performRequest(e, "GET", tt.path, nil)
+		if got := w.Header().Get("Content-Type"); got != tt.want {
+			t.Errorf("GET %s: Content-Type = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestResponseWrapper(t *testing.T) {
+	type user struct {
+		Name string `json:"name"`
+	}
+	e := New()
+	e.SetResponseWrapper(EnvelopeWrapper)
+	e.GET("/user", func(c *Context) {
+		c.JSON(200, user{"ann"})
+	})
+	e.GET("/partial", func(c *Context) {
+		c.Error(errors.New("avatar unavailable"), nil)
+		c.JSON(200, user{"bob"})
+	})
+	e.GET("/fail", WrapErr(func(c *Context) error {
+		return errors.New("internal")
+	}))
+
+	tests := []struct {
+		path, want string
+	}{
+		{"/user", `{"data":{"name":"ann"},"error":null}`},
+		{"/partial", `{"data":{"name":"bob"},"error":"avatar unavailable"}`},
+		// the error bodies of the engine aren't wrapped
+		{"/fail", `{"error":"Internal Server Error"}`},
+	}
+	for _, tt := range tests {
+		w := performRequest(e, "GET", tt.path, nil)
+		if got := strings.TrimSpace(w.Body.String()); got != tt.want {
+			t.Errorf("GET %s: body = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestOnce(t *testing.T) {
+	calls := 0
+	claims := func(c *Context) string {
+		return c.Once("claims", func() interface{} {
+			calls++
+			return "ann"
+		}).(string)
+	}
+	e := New()
+	e.Use(func(c *Context) {
+		claims(c)
+		c.Next()
+	})
+	e.GET("/", func(c *Context) {
+		claims(c)
+		c.String(200, claims(c))
+	})
+
+	w := performRequest(e, "GET", "/", nil)
+	if w.Body.String() != "ann" || calls != 1 {
+		t.Errorf("got %q after %d computations, want ann after 1", w.Body.String(), calls)
+	}
+	// the value lives as long as the request
+	performRequest(e, "GET", "/", nil)
+	if calls != 2 {
+		t.Errorf("%d computations over two requests, want 2", calls)
+	}
+}
@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"net/http"
+	"reflect"
+)
+
+// StatusError is implemented by the errors knowing the HTTP status they should be answered with
+type StatusError interface {
+	error
+	StatusCode() int
+}
+
+// a sentinel error registered with Engine.MapError
+type mappedError struct {
+	err    error
+	status int
+}
+
+// MapError makes the error responses of err, or of an error wrapping it, use status,
+// e.g. engine.MapError(sql.ErrNoRows, 404). See WrapErr and ErrorHandler.
+func (engine *Engine) MapError(err error, status int) {
+	engine.errorMap = append(engine.errorMap, mappedError{err, status})
+}
+
+// MapErrorFunc registers a function returning the status and the JSON body of the error responses,
+// a 0 status lets the next mappers handle the error. The functions are tried in order after the MapError errors.
+func (engine *Engine) MapErrorFunc(fn func(error) (int, interface{})) {
+	engine.errorFuncs = append(engine.errorFuncs, fn)
+}
+
+// matchError reports whether err is target or wraps it
+func matchError(err, target error) bool {
+	canCompare := reflect.TypeOf(target).Comparable()
+	for err != nil {
+		if canCompare && err == target {
+			return true
+		}
+		wrapper, ok := err.(interface{ Unwrap() error })
+		if !ok {
+			return false
+		}
+		err = wrapper.Unwrap()
+	}
+	return false
+}
+
+// ErrorHandler returns a middleware owning the error responses: once the handlers return,
+// if they recorded errors and wrote nothing, render is called with the errors to write the response.
+// With a nil render, the first error is answered like WrapErr does, with the status and the body it maps to,
+// see Engine.MapError, and an "errors" list of the messages of the errors mapped by MapError or a StatusError.
+// The other messages and the Meta of the errors stay out of the response, they may carry internal details.
+// Unlike ErrorLogger, nothing happens when the response was already written.
+func ErrorHandler(render func(*Context, []ErrorMsg)) HandlerFunc {
+	if render == nil {
+		render = defaultErrorRender
+	}
+	return func(c *Context) {
+		c.Next()
+		if len(c.Errors) > 0 && !c.responseStarted() {
+			render(c, c.Errors)
+		}
+	}
+}
+
+func defaultErrorRender(c *Context, errs []ErrorMsg) {
+	code, body := c.mapError(errs[0].err)
+	var messages []string
+	for _, e := range errs {
+		if msg, ok := c.publicMessage(e.err); ok {
+			messages = append(messages, msg)
+		}
+	}
+	if h, ok := body.(H); ok && len(messages) > 0 {
+		envelope := H{"errors": messages}
+		for k, v := range h {
+			envelope[k] = v
+		}
+		body = envelope
+	}
+	c.renderJSON(code, body)
+}
+
+// mapError returns the status and the response body of err, from the first match of:
+// the errors registered with Engine.MapError, the functions of Engine.MapErrorFunc, a StatusError with its message.
+// Otherwise it is a 500 whose body doesn't leak the error.
+func (c *Context) mapError(err error) (int, interface{}) {
+	if err != nil && c.engine != nil {
+		for _, m := range c.engine.errorMap {
+			if matchError(err, m.err) {
+				return m.status, H{"error": err.Error()}
+			}
+		}
+		for _, fn := range c.engine.errorFuncs {
+			if status, body := fn(err); status != 0 {
+				return status, body
+			}
+		}
+	}
+	if se, ok := err.(StatusError); ok {
+		return se.StatusCode(), H{"error": se.Error()}
+	}
+	return http.StatusInternalServerError, H{"error": http.StatusText(http.StatusInternalServerError)}
+}
+
+// publicMessage returns the message of err when mapError exposes it, for the errors of Engine.MapError and the StatusErrors
+func (c *Context) publicMessage(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+	if c.engine != nil {
+		for _, m := range c.engine.errorMap {
+			if matchError(err, m.err) {
+				return err.Error(), true
+			}
+		}
+	}
+	if se, ok := err.(StatusError); ok {
+		return se.Error(), true
+	}
+	return "", false
+}
+
+// renderError records err, skips the pending handlers and answers with the mapped response
+func (c *Context) renderError(err error) {
+	c.Error(err, "Operation aborted")
+	c.index = AbortIndex
+	if c.responseStarted() {
+		return
+	}
+	code, body := c.mapError(err)
+	c.renderJSON(code, body)
+}
@@ -0,0 +1,116 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestErrorHandlerEnvelope(t *testing.T) {
+	errNotFound := errors.New("user not found")
+	e := New()
+	e.MapError(errNotFound, 404)
+	e.Use(ErrorHandler(nil))
+	e.GET("/users/:id", func(c *Context) {
+		c.Error(errNotFound, "looking up the user")
+		c.Error(errors.New("cache miss"), nil)
+	})
+	e.GET("/internal", func(c *Context) {
+		c.Error(errors.New("dial tcp: postgres://admin:secret@db"), H{"dsn": "postgres://admin:secret@db"})
+	})
+	e.GET("/written", func(c *Context) {
+		c.Error(errNotFound, nil)
+		c.String(200, "partial")
+	})
+
+	w := performRequest(e, "GET", "/users/1", nil)
+	if w.Code != 404 {
+		t.Errorf("status = %d, want 404", w.Code)
+	}
+	var envelope struct {
+		Error  string   `json:"error"`
+		Errors []string `json:"errors"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &envelope); err != nil {
+		t.Fatalf("the body %q isn't an envelope: %v", w.Body.String(), err)
+	}
+	// the unmapped "cache miss" and the meta stay out of the response
+	if envelope.Error != "user not found" || len(envelope.Errors) != 1 || envelope.Errors[0] != "user not found" {
+		t.Errorf("envelope = %+v", envelope)
+	}
+	if strings.Contains(w.Body.String(), "cache miss") || strings.Contains(w.Body.String(), "looking up") {
+		t.Errorf("body = %s, an internal detail leaked", w.Body.String())
+	}
+
+	w = performRequest(e, "GET", "/internal", nil)
+	if want := `{"error":"Internal Server Error"}`; w.Code != 500 || strings.TrimSpace(w.Body.String()) != want {
+		t.Errorf("an unmapped error: got %d %s, want 500 %s", w.Code, w.Body.String(), want)
+	}
+
+	w = performRequest(e, "GET", "/written", nil)
+	if w.Code != 200 || w.Body.String() != "partial" {
+		t.Errorf("a written response: got %d %q, want it untouched", w.Code, w.Body.String())
+	}
+}
+
+func TestErrorHandlerCustomRender(t *testing.T) {
+	e := New()
+	e.Use(ErrorHandler(func(c *Context, errs []ErrorMsg) {
+		c.String(418, errs[0].Err)
+	}))
+	e.GET("/", func(c *Context) {
+		c.Error(errors.New("teapot"), nil)
+	})
+	w := performRequest(e, "GET", "/", nil)
+	if w.Code != 418 || w.Body.String() != "teapot" {
+		t.Errorf("got %d %q", w.Code, w.Body.String())
+	}
+}
+
+// wrappedError wraps err like fmt.Errorf("...: %w") does on newer Go versions
+type wrappedError struct {
+	msg string
+	err error
+}
+
+func (e wrappedError) Error() string { return e.msg + ": " + e.err.Error() }
+func (e wrappedError) Unwrap() error { return e.err }
+
+func TestMapError(t *testing.T) {
+	errNoRows := errors.New("no rows in result set")
+	errConflict := errors.New("conflict")
+	e := New()
+	e.MapError(errNoRows, 404)
+	e.MapErrorFunc(func(err error) (int, interface{}) {
+		if err == errConflict {
+			return 409, H{"error": "already exists", "retry": false}
+		}
+		return 0, nil
+	})
+	serve := func(err error) func(*Context) error {
+		return func(c *Context) error { return err }
+	}
+	e.GET("/sentinel", WrapErr(serve(errNoRows)))
+	e.GET("/wrapped", WrapErr(serve(wrappedError{"loading user 1", errNoRows})))
+	e.GET("/func", WrapErr(serve(errConflict)))
+	e.GET("/unmapped", WrapErr(serve(errors.New("db password is hunter2"))))
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/sentinel", 404, `{"error":"no rows in result set"}`},
+		{"/wrapped", 404, `{"error":"loading user 1: no rows in result set"}`},
+		{"/func", 409, `{"error":"already exists","retry":false}`},
+		// the unmapped errors don't leak
+		{"/unmapped", 500, `{"error":"Internal Server Error"}`},
+	}
+	for _, tt := range tests {
+		w := performRequest(e, "GET", tt.path, nil)
+		if w.Code != tt.code || strings.TrimSpace(w.Body.String()) != tt.body {
+			t.Errorf("GET %s: got %d %q, want %d %s", tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+}
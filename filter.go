@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// Like JSON() but, when the request has a `fields` query parameter (e.g. `?fields=id,name`),
+// only the listed top-level fields of the object are serialized. The names are the json names
+// of the fields, after Engine.JSONFieldNaming, and unknown ones are ignored. A slice of objects is filtered element by element.
+func (c *Context) FilteredJSON(code int, obj interface{}) {
+	fields, ok := c.GetQuery("fields")
+	if !ok {
+		c.JSON(code, obj)
+		return
+	}
+	named, err := c.nameFields(obj)
+	if err != nil {
+		c.Fail(500, err)
+		return
+	}
+	filtered, err := filterFields(named, strings.Split(fields, ","))
+	if err != nil {
+		c.Fail(500, err)
+		return
+	}
+	c.JSON(code, filtered)
+}
+
+// filterFields keeps the given top-level keys of the json representation of obj
+func filterFields(obj interface{}, fields []string) (interface{}, error) {
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[string]bool, len(fields))
+	for _, f := range fields {
+		if f = strings.TrimSpace(f); f != "" {
+			keep[f] = true
+		}
+	}
+
+	var list []map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &list); err == nil {
+		for _, object := range list {
+			filterObject(object, keep)
+		}
+		return list, nil
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &object); err != nil {
+		// neither an object nor a list of objects, nothing to filter
+		return obj, nil
+	}
+	filterObject(object, keep)
+	return object, nil
+}
+
+func filterObject(object map[string]json.RawMessage, keep map[string]bool) {
+	for key := range object {
+		if !keep[key] {
+			delete(object, key)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFilteredJSON(t *testing.T) {
+	type user struct {
+		ID    int    `json:"id"`
+		Name  string `json:"name"`
+		Email string `json:"email"`
+	}
+	e := New()
+	e.GET("/user", func(c *Context) {
+		c.FilteredJSON(200, user{ID: 1, Name: "ann", Email: "ann@example.com"})
+	})
+	e.GET("/users", func(c *Context) {
+		c.FilteredJSON(200, []user{{ID: 1, Name: "ann"}, {ID: 2, Name: "bob"}})
+	})
+
+	tests := []struct {
+		path, want string
+	}{
+		{"/user", `{"id":1,"name":"ann","email":"ann@example.com"}`},
+		{"/user?fields=id,name", `{"id":1,"name":"ann"}`},
+		{"/user?fields=name,unknown", `{"name":"ann"}`},
+		{"/users?fields=name", `[{"name":"ann"},{"name":"bob"}]`},
+	}
+	for _, tt := range tests {
+		w := performRequest(e, "GET", tt.path, nil)
+		if got := strings.TrimSpace(w.Body.String()); got != tt.want {
+			t.Errorf("GET %s: body = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestFilteredJSONAfterFieldNaming(t *testing.T) {
+	type user struct {
+		UserID   int
+		UserName string
+	}
+	e := New()
+	e.JSONFieldNaming = SnakeCase
+	e.GET("/user", func(c *Context) {
+		c.FilteredJSON(200, user{UserID: 1, UserName: "ann"})
+	})
+	w := performRequest(e, "GET", "/user?fields=user_name", nil)
+	if got := strings.TrimSpace(w.Body.String()); got != `{"user_name":"ann"}` {
+		t.Errorf("body = %s, want {\"user_name\":\"ann\"}", got)
+	}
+}
@@ -2,4 +2,8 @@ module github.com/mind1949/engine
 
 go 1.12
 
-require github.com/julienschmidt/httprouter v1.3.0
+require (
+	github.com/golang-jwt/jwt v3.2.2+incompatible
+	github.com/julienschmidt/httprouter v1.3.0
+	golang.org/x/sync v0.1.0
+)
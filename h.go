@@ -0,0 +1,63 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+type (
+	// HOrdered is like H but keeps the insertion order of its keys when marshalled to JSON:
+	//	c.JSON(200, engine.HOrdered{{"status", "ok"}, {"count", 2}})
+	// Note that H is a map and encoding/json already marshals maps with their keys sorted.
+	HOrdered []KeyValue
+
+	// a single entry of an HOrdered
+	KeyValue struct {
+		Key   string
+		Value interface{}
+	}
+)
+
+// Set replaces the value of key, or appends it when the key is new.
+func (h *HOrdered) Set(key string, value interface{}) {
+	for i := range *h {
+		if (*h)[i].Key == key {
+			(*h)[i].Value = value
+			return
+		}
+	}
+	*h = append(*h, KeyValue{key, value})
+}
+
+// Get returns the value of key and whether it exists.
+func (h HOrdered) Get(key string) (interface{}, bool) {
+	for _, kv := range h {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return nil, false
+}
+
+func (h HOrdered) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, kv := range h {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(kv.Key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(kv.Value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,31 @@
+package engine
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestHOrderedMarshalsInInsertionOrder(t *testing.T) {
+	h := HOrdered{{"zeta", 1}, {"alpha", "a"}, {"mid", []int{1, 2}}}
+	h.Set("alpha", "b")
+	h.Set("omega", nil)
+
+	first, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := json.Marshal(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"zeta":1,"alpha":"b","mid":[1,2],"omega":null}`
+	if string(first) != want || string(second) != want {
+		t.Errorf("got %s then %s, want %s", first, second, want)
+	}
+	if v, ok := h.Get("mid"); !ok || len(v.([]int)) != 2 {
+		t.Errorf("Get(mid) = %v, %v", v, ok)
+	}
+	if _, ok := h.Get("missing"); ok {
+		t.Error("Get(missing) found a value")
+	}
+}
@@ -0,0 +1,37 @@
+package engine
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// AllowedHosts returns a middleware that aborts with a 400 when the request Host isn't listed.
+// A host starting with "*." matches any subdomain, e.g. "*.example.com" allows "api.example.com"
+// but not "example.com". Ports and case are ignored.
+func AllowedHosts(hosts []string) HandlerFunc {
+	allowed := make([]string, len(hosts))
+	for i, h := range hosts {
+		allowed[i] = normalizeHost(h)
+	}
+	return func(c *Context) {
+		host := normalizeHost(c.Req.Host)
+		for _, h := range allowed {
+			if host == h || (strings.HasPrefix(h, "*.") && strings.HasSuffix(host, h[1:])) {
+				c.Next()
+				return
+			}
+		}
+		c.Error(errors.New("invalid host "+c.Req.Host), "host validation")
+		c.Abort(http.StatusBadRequest)
+	}
+}
+
+// normalizeHost strips the port and the trailing dot, and lowercases the host
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
@@ -0,0 +1,28 @@
+package engine
+
+import "testing"
+
+func TestAllowedHosts(t *testing.T) {
+	e := New()
+	e.Use(AllowedHosts([]string{"example.com", "*.example.org"}))
+	e.GET("/", func(c *Context) {
+		c.String(200, "ok")
+	})
+
+	tests := []struct {
+		url  string
+		code int
+	}{
+		{"http://example.com/", 200},
+		{"http://EXAMPLE.com:8080/", 200},
+		{"http://api.example.org/", 200},
+		{"http://example.org/", 400},
+		{"http://evil.com/", 400},
+		{"http://example.com.evil.com/", 400},
+	}
+	for _, tt := range tests {
+		if w := performRequest(e, "GET", tt.url, nil); w.Code != tt.code {
+			t.Errorf("GET %s: status = %d, want %d", tt.url, w.Code, tt.code)
+		}
+	}
+}
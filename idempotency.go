@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// the request header carrying the idempotency key
+	IdempotencyKeyHeader = "Idempotency-Key"
+
+	// how long the default store keeps the responses
+	DefaultIdempotencyTTL = 24 * time.Hour
+)
+
+type (
+	// IdemResponse is a response saved by the Idempotency middleware
+	IdemResponse = BufferedResponse
+
+	// IdemStore keeps the responses of the Idempotency middleware, it is in charge of expiring them
+	IdemStore interface {
+		// Get returns the response saved for key, or false
+		Get(key string) (*IdemResponse, bool)
+
+		// Set saves the response of key
+		Set(key string, response *IdemResponse)
+	}
+
+	idemMemoryStore struct {
+		cache *responseCache
+		ttl   time.Duration
+	}
+)
+
+// NewIdemMemoryStore returns an in-memory IdemStore keeping the responses for ttl
+func NewIdemMemoryStore(ttl time.Duration) IdemStore {
+	return &idemMemoryStore{cache: &responseCache{entries: map[string]cacheEntry{}}, ttl: ttl}
+}
+
+func (s *idemMemoryStore) Get(key string) (*IdemResponse, bool) {
+	response := s.cache.get(key, time.Now())
+	if response == nil {
+		return nil, false
+	}
+	return response.buffered(), true
+}
+
+func (s *idemMemoryStore) Set(key string, response *IdemResponse) {
+	s.cache.set(key, response.recorded(), time.Now(), s.ttl)
+}
+
+// Idempotency returns a middleware making the retries of unsafe requests (POST, PUT, PATCH, DELETE)
+// carrying an Idempotency-Key header safe: the first request runs the handlers and its response is saved,
+// the next ones with the same key, method and path get the saved response, with an Idempotent-Replayed header,
+// without calling the pending handlers. Concurrent requests with the same key wait for the first one.
+// The 5xx responses and the ones with errors aren't saved so they can be retried.
+// When store is nil, the responses are kept in memory for DefaultIdempotencyTTL.
+func Idempotency(store IdemStore) HandlerFunc {
+	if store == nil {
+		store = NewIdemMemoryStore(DefaultIdempotencyTTL)
+	}
+	var group singleflight.Group
+	return func(c *Context) {
+		idemKey := c.Req.Header.Get(IdempotencyKeyHeader)
+		switch c.Req.Method {
+		case "GET", "HEAD", "OPTIONS", "TRACE":
+			idemKey = ""
+		}
+		if idemKey == "" {
+			c.Next()
+			return
+		}
+
+		key := c.Req.Method + " " + c.Req.URL.Path + " " + idemKey
+		if response, ok := store.Get(key); ok {
+			replayIdemResponse(c, response)
+			return
+		}
+
+		executed := false
+		v, _, _ := group.Do(key, func() (interface{}, error) {
+			// answered while waiting for the lock of the group
+			if response, ok := store.Get(key); ok {
+				return response, nil
+			}
+			executed = true
+			w := newBufferWriter(c.Writer)
+			c.Writer = w
+			defer func() {
+				c.Writer = w.ResponseWriter
+			}()
+			c.Next()
+			c.writeStatusText()
+
+			response := w.response().buffered()
+			if response.Status < 500 && len(c.Errors) == 0 {
+				store.Set(key, response)
+			}
+			return response, nil
+		})
+		if executed {
+			v.(*IdemResponse).recorded().writeTo(c.Writer)
+			return
+		}
+		replayIdemResponse(c, v.(*IdemResponse))
+	}
+}
+
+// replayIdemResponse writes a saved response and skips the pending handlers
+func replayIdemResponse(c *Context, response *IdemResponse) {
+	c.Writer.Header().Set("Idempotent-Replayed", "true")
+	response.recorded().writeTo(c.Writer)
+	c.index = AbortIndex
+}
@@ -0,0 +1,65 @@
+package engine
+
+import (
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestIdempotency(t *testing.T) {
+	calls := 0
+	e := New()
+	e.Use(Idempotency(nil))
+	e.POST("/payments", func(c *Context) {
+		calls++
+		c.Writer.Header().Set("X-Payment", strconv.Itoa(calls))
+		c.String(201, "payment "+strconv.Itoa(calls))
+	})
+	e.POST("/refunds", func(c *Context) {
+		calls++
+		c.String(201, "refund")
+	})
+
+	first := performRequest(e, "POST", "/payments", nil, IdempotencyKeyHeader, "k1")
+	if calls != 1 || first.Code != 201 || first.Body.String() != "payment 1" {
+		t.Fatalf("first request: %d calls, got %d %q", calls, first.Code, first.Body.String())
+	}
+	if first.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("the first request is marked as replayed")
+	}
+
+	replay := performRequest(e, "POST", "/payments", nil, IdempotencyKeyHeader, "k1")
+	if calls != 1 {
+		t.Errorf("the handler ran again for a repeated key")
+	}
+	if replay.Code != 201 || replay.Body.String() != "payment 1" || replay.Header().Get("X-Payment") != "1" {
+		t.Errorf("replay: got %d %q with X-Payment %q", replay.Code, replay.Body.String(), replay.Header().Get("X-Payment"))
+	}
+	if replay.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("the replay isn't marked as replayed")
+	}
+
+	// the keys are scoped by route, and requests without a key always run
+	performRequest(e, "POST", "/refunds", nil, IdempotencyKeyHeader, "k1")
+	performRequest(e, "POST", "/payments", nil)
+	performRequest(e, "POST", "/payments", nil, IdempotencyKeyHeader, "k2")
+	if calls != 4 {
+		t.Errorf("%d calls, want 4", calls)
+	}
+}
+
+func TestIdempotencyExpiry(t *testing.T) {
+	calls := 0
+	e := New()
+	e.Use(Idempotency(NewIdemMemoryStore(10 * time.Millisecond)))
+	e.POST("/", func(c *Context) {
+		calls++
+		c.String(200, "ok")
+	})
+	performRequest(e, "POST", "/", nil, IdempotencyKeyHeader, "k")
+	time.Sleep(20 * time.Millisecond)
+	performRequest(e, "POST", "/", nil, IdempotencyKeyHeader, "k")
+	if calls != 2 {
+		t.Errorf("%d calls, want the expired key to run again", calls)
+	}
+}
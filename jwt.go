@@ -0,0 +1,70 @@
+package engine
+
+import (
+	"errors"
+	"github.com/golang-jwt/jwt"
+	"net/http"
+	"strings"
+)
+
+// the key under which the JWT middleware stores the token claims
+const ClaimsKey = "claims"
+
+// JWTConfig configures the JWTWithConfig middleware
+type JWTConfig struct {
+	// KeyFunc returns the key verifying the token signature, it is required
+	KeyFunc jwt.Keyfunc
+
+	// Header is the request header carrying the token, Authorization by default
+	Header string
+
+	// Prefix is expected before the token in the header, "Bearer " by default
+	Prefix string
+}
+
+// JWT returns a middleware validating the bearer token of the Authorization header.
+// The claims of a valid token are available with Context.Claims(), the request is aborted
+// with a 401 when the token is missing, invalid or expired.
+func JWT(keyFunc jwt.Keyfunc) HandlerFunc {
+	return JWTWithConfig(JWTConfig{KeyFunc: keyFunc})
+}
+
+// Like JWT() but reads the token from the given header and prefix, see JWTConfig.
+func JWTWithConfig(config JWTConfig) HandlerFunc {
+	if config.KeyFunc == nil {
+		panic("engine: JWT middleware requires a KeyFunc")
+	}
+	if config.Header == "" {
+		config.Header = "Authorization"
+	}
+	if config.Prefix == "" {
+		config.Prefix = "Bearer "
+	}
+	return func(c *Context) {
+		auth := c.Req.Header.Get(config.Header)
+		if auth == "" || !strings.HasPrefix(auth, config.Prefix) {
+			c.Writer.Header().Set("WWW-Authenticate", "Bearer")
+			c.Fail(http.StatusUnauthorized, errors.New("missing token"))
+			return
+		}
+
+		claims := jwt.MapClaims{}
+		token, err := jwt.ParseWithClaims(strings.TrimPrefix(auth, config.Prefix), claims, config.KeyFunc)
+		if err == nil && !token.Valid {
+			err = errors.New("invalid token")
+		}
+		if err != nil {
+			c.Writer.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			c.Fail(http.StatusUnauthorized, err)
+			return
+		}
+		c.Set(ClaimsKey, claims)
+		c.Next()
+	}
+}
+
+// Returns the claims of the token validated by the JWT middleware, nil otherwise.
+func (c *Context) Claims() jwt.MapClaims {
+	claims, _ := c.Keys[ClaimsKey].(jwt.MapClaims)
+	return claims
+}
@@ -0,0 +1,68 @@
+package engine
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+var jwtTestKey = []byte("secret")
+
+func signToken(t *testing.T, claims jwt.MapClaims) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(jwtTestKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestJWT(t *testing.T) {
+	var subject interface{}
+	e := New()
+	e.Use(JWT(func(token *jwt.Token) (interface{}, error) {
+		return jwtTestKey, nil
+	}))
+	e.GET("/me", func(c *Context) {
+		subject = c.Claims()["sub"]
+		c.String(200, "ok")
+	})
+
+	valid := signToken(t, jwt.MapClaims{"sub": "ann", "exp": time.Now().Add(time.Hour).Unix()})
+	w := performRequest(e, "GET", "/me", nil, "Authorization", "Bearer "+valid)
+	if w.Code != 200 || subject != "ann" {
+		t.Errorf("valid token: got %d with subject %v", w.Code, subject)
+	}
+
+	subject = nil
+	expired := signToken(t, jwt.MapClaims{"sub": "ann", "exp": time.Now().Add(-time.Hour).Unix()})
+	w = performRequest(e, "GET", "/me", nil, "Authorization", "Bearer "+expired)
+	if w.Code != 401 || subject != nil {
+		t.Errorf("expired token: got %d with subject %v", w.Code, subject)
+	}
+	if got := w.Header().Get("WWW-Authenticate"); got != `Bearer error="invalid_token"` {
+		t.Errorf("expired token: WWW-Authenticate = %q", got)
+	}
+
+	w = performRequest(e, "GET", "/me", nil)
+	if w.Code != 401 || subject != nil {
+		t.Errorf("missing header: got %d with subject %v", w.Code, subject)
+	}
+}
+
+func TestJWTWithConfig(t *testing.T) {
+	e := New()
+	e.Use(JWTWithConfig(JWTConfig{
+		KeyFunc: func(token *jwt.Token) (interface{}, error) { return jwtTestKey, nil },
+		Header:  "X-Token",
+		Prefix:  "Token ",
+	}))
+	e.GET("/me", func(c *Context) {
+		c.String(200, c.Claims()["sub"].(string))
+	})
+	token := signToken(t, jwt.MapClaims{"sub": "bob"})
+	w := performRequest(e, "GET", "/me", nil, "X-Token", "Token "+token)
+	if w.Code != 200 || w.Body.String() != "bob" {
+		t.Errorf("got %d %q, want 200 bob", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,29 @@
+package engine
+
+import (
+	"net/http"
+)
+
+// MaxConcurrent returns a middleware that serves at most n requests at the same time.
+// Requests over the limit are not queued, they are answered with a 503 and a Retry-After header.
+// It panics if n isn't positive.
+func MaxConcurrent(n int) HandlerFunc {
+	if n <= 0 {
+		panic("engine: the limit of concurrent requests must be positive")
+	}
+	sem := make(chan struct{}, n)
+	return func(c *Context) {
+		select {
+		case sem <- struct{}{}:
+		default:
+			c.Writer.Header().Set("Retry-After", "1")
+			c.Abort(http.StatusServiceUnavailable)
+			return
+		}
+		// released even if a handler panics
+		defer func() {
+			<-sem
+		}()
+		c.Next()
+	}
+}
@@ -0,0 +1,54 @@
+package engine
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestMaxConcurrent(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	e := New()
+	e.Use(MaxConcurrent(1))
+	e.GET("/slow", func(c *Context) {
+		close(started)
+		<-release
+		c.String(200, "done")
+	})
+	e.GET("/fast", func(c *Context) {
+		c.String(200, "fast")
+	})
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if w := performRequest(e, "GET", "/slow", nil); w.Code != 200 {
+			t.Errorf("slow request: status = %d, want 200", w.Code)
+		}
+	}()
+	<-started
+
+	w := performRequest(e, "GET", "/fast", nil)
+	if w.Code != 503 {
+		t.Errorf("saturated: status = %d, want 503", w.Code)
+	}
+	if got := w.Header().Get("Retry-After"); got != "1" {
+		t.Errorf("saturated: Retry-After = %q, want 1", got)
+	}
+
+	close(release)
+	wg.Wait()
+	if w := performRequest(e, "GET", "/fast", nil); w.Code != 200 {
+		t.Errorf("after release: status = %d, want 200", w.Code)
+	}
+}
+
+func TestMaxConcurrentInvalidLimit(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("MaxConcurrent(0) didn't panic")
+		}
+	}()
+	MaxConcurrent(0)
+}
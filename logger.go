@@ -1,17 +1,86 @@
 package engine
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"log"
+	"mime"
 	"time"
 )
 
+// the key under which a tracing middleware stores the trace id of the request, for the logs
+const TraceIDKey = "traceID"
+
+type (
+	// LogEntry holds the fields of an access log line
+	LogEntry struct {
+		Time       time.Time
+		Method     string
+		RequestURI string
+		Status     int
+		Latency    time.Duration
+		Errors     ErrorMsgs
+
+		// bytes read from the request body and written to the response body
+		RequestSize  int64
+		ResponseSize int
+
+		// set when the RequestID middleware, or a tracing one storing TraceIDKey, ran
+		RequestID string
+		TraceID   string
+
+		// only filled when enabled in the LoggerConfig
+		UserAgent string
+		Referer   string
+	}
+
+	// LogFormatter turns an entry into a log line
+	LogFormatter func(entry LogEntry) string
+
+	// LoggerConfig configures the LoggerWithConfig middleware
+	LoggerConfig struct {
+		// Output receives the log lines, the standard logger is used by default
+		Output io.Writer
+
+		// Formatter builds the log lines, defaultLogFormatter by default
+		Formatter LogFormatter
+
+		// UserAgent and Referer add the corresponding request headers to the entries
+		UserAgent bool
+		Referer   bool
+
+		// OnlyErrors only logs the responses with a status >= 400 or with recorded errors
+		OnlyErrors bool
+	}
+)
+
+// defaultLogFormatter writes the request uri and the resolution time, plus the ids and the optional fields
+func defaultLogFormatter(entry LogEntry) string {
+	line := fmt.Sprintf("%s in %v", entry.RequestURI, entry.Latency)
+	if entry.RequestID != "" {
+		line += " request_id=" + entry.RequestID
+	}
+	if entry.TraceID != "" {
+		line += " trace_id=" + entry.TraceID
+	}
+	if entry.UserAgent != "" {
+		line += fmt.Sprintf(" user-agent=%q", entry.UserAgent)
+	}
+	if entry.Referer != "" {
+		line += fmt.Sprintf(" referer=%q", entry.Referer)
+	}
+	return line
+}
+
 func (c *Context) ErrorLogger() HandlerFunc {
 	return func(c *Context) {
 		defer func() {
 			if len(c.Errors) > 0 {
 				fmt.Printf("%s\n", c.Errors)
-				c.JSON(-1, c.Errors)
+				c.renderJSON(-1, c.Errors)
 			}
 		}()
 		c.Next()
@@ -19,6 +88,18 @@ func (c *Context) ErrorLogger() HandlerFunc {
 }
 
 func Logger() HandlerFunc {
+	return LoggerWithConfig(LoggerConfig{})
+}
+
+// Like Logger() with more options, see LoggerConfig.
+func LoggerWithConfig(config LoggerConfig) HandlerFunc {
+	if config.Formatter == nil {
+		config.Formatter = defaultLogFormatter
+	}
+	output := log.Print
+	if config.Output != nil {
+		output = log.New(config.Output, "", log.LstdFlags).Print
+	}
 	return func(c *Context) {
 
 		// Start time
@@ -27,7 +108,60 @@ func Logger() HandlerFunc {
 		// Process request
 		c.Next()
 
+		if config.OnlyErrors && c.Writer.Status() < 400 && len(c.Errors) == 0 {
+			return
+		}
+
 		// Calculate request resolution time
-		log.Printf("%s in %v", c.Req.RequestURI, time.Since(t))
+		entry := LogEntry{
+			Time:       t,
+			Method:     c.Req.Method,
+			RequestURI: c.Req.RequestURI,
+			Status:     c.Writer.Status(),
+			Latency:    time.Since(t),
+			Errors:     c.Errors,
+			RequestID:  c.RequestID(),
+
+			RequestSize:  c.RequestSize(),
+			ResponseSize: c.Writer.Size(),
+		}
+		entry.TraceID, _ = c.Keys[TraceIDKey].(string)
+		if config.UserAgent {
+			entry.UserAgent = c.Req.UserAgent()
+		}
+		if config.Referer {
+			entry.Referer = c.Req.Referer()
+		}
+		output(config.Formatter(entry))
+	}
+}
+
+// the maximum body size pretty-printed by LogJSONBody
+const maxLoggedJSONBody = 64 << 10
+
+// LogJSONBody returns a middleware logging the indented JSON bodies of the requests, for debugging.
+// It only logs in Debug mode and for bodies up to 64KB, the body is restored for the handlers.
+func LogJSONBody() HandlerFunc {
+	return func(c *Context) {
+		mediaType, _, _ := mime.ParseMediaType(c.Req.Header.Get("Content-Type"))
+		if !c.engine.Debug || mediaType != MIMEJSON || c.Req.Body == nil {
+			c.Next()
+			return
+		}
+		body, err := ioutil.ReadAll(io.LimitReader(c.Req.Body, maxLoggedJSONBody+1))
+		// give back what was read, followed by what is left
+		c.Req.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), c.Req.Body), c.Req.Body}
+		if err == nil && len(body) <= maxLoggedJSONBody {
+			var indented bytes.Buffer
+			if json.Indent(&indented, body, "", "  ") == nil {
+				log.Printf("%s %s body:\n%s", c.Req.Method, c.Req.RequestURI, indented.String())
+			} else {
+				log.Printf("%s %s invalid JSON body: %s", c.Req.Method, c.Req.RequestURI, body)
+			}
+		}
+		c.Next()
 	}
 }
@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+// logRequest serves a GET of path through the logger configured by config and returns its output
+func logRequest(config LoggerConfig, path string, handler HandlerFunc, headers ...string) string {
+	var out bytes.Buffer
+	config.Output = &out
+	e := New()
+	e.Use(LoggerWithConfig(config))
+	e.GET(path, handler)
+	performRequest(e, "GET", path, nil, headers...)
+	return out.String()
+}
+
+func TestLoggerUserAgentAndReferer(t *testing.T) {
+	ok := func(c *Context) { c.String(200, "ok") }
+	headers := []string{"User-Agent", "curl/7.0", "Referer", "https://example.com/"}
+
+	line := logRequest(LoggerConfig{}, "/", ok, headers...)
+	if strings.Contains(line, "user-agent=") || strings.Contains(line, "referer=") {
+		t.Errorf("the fields are logged by default: %q", line)
+	}
+
+	line = logRequest(LoggerConfig{UserAgent: true, Referer: true}, "/", ok, headers...)
+	if !strings.Contains(line, `user-agent="curl/7.0"`) || !strings.Contains(line, `referer="https://example.com/"`) {
+		t.Errorf("the enabled fields are missing: %q", line)
+	}
+
+	var entry LogEntry
+	logRequest(LoggerConfig{UserAgent: true, Formatter: func(e LogEntry) string {
+		entry = e
+		return ""
+	}}, "/", ok, headers...)
+	if entry.UserAgent != "curl/7.0" || entry.Referer != "" {
+		t.Errorf("entry has UserAgent %q and Referer %q", entry.UserAgent, entry.Referer)
+	}
+}
+
+func TestLogJSONBody(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	defer log.SetOutput(os.Stderr)
+
+	var got struct {
+		Name string `json:"name"`
+	}
+	e := New()
+	e.Use(LogJSONBody())
+	e.POST("/users", func(c *Context) {
+		if c.EnsureBody(&got) {
+			c.String(201, got.Name)
+		}
+	})
+
+	w := performRequest(e, "POST", "/users", strings.NewReader(`{"name":"ann"}`), "Content-Type", MIMEJSON)
+	if w.Code != 201 || got.Name != "ann" {
+		t.Errorf("the body isn't bindable after logging: got %d %+v", w.Code, got)
+	}
+	if out.Len() != 0 {
+		t.Errorf("logged outside of Debug mode: %q", out.String())
+	}
+
+	e.Debug = true
+	got.Name = ""
+	w = performRequest(e, "POST", "/users", strings.NewReader(`{"name":"ann"}`), "Content-Type", MIMEJSON)
+	if w.Code != 201 || got.Name != "ann" {
+		t.Errorf("the body isn't bindable after logging: got %d %+v", w.Code, got)
+	}
+	if !strings.Contains(out.String(), "POST /users body:\n{\n  \"name\": \"ann\"\n}") {
+		t.Errorf("logged %q, want the indented body", out.String())
+	}
+}
+
+func TestLoggerOnlyErrors(t *testing.T) {
+	config := LoggerConfig{OnlyErrors: true}
+	tests := []struct {
+		name    string
+		handler HandlerFunc
+		logged  bool
+	}{
+		{"200", func(c *Context) { c.String(200, "ok") }, false},
+		{"204", func(c *Context) { c.NoContent() }, false},
+		{"404", func(c *Context) { c.Status(404) }, true},
+		{"500", func(c *Context) { c.Abort(500) }, true},
+		{"recorded error", func(c *Context) {
+			c.Error(errors.New("cache down"), nil)
+			c.String(200, "degraded")
+		}, true},
+	}
+	for _, tt := range tests {
+		line := logRequest(config, "/", tt.handler)
+		if logged := line != ""; logged != tt.logged {
+			t.Errorf("%s: logged %q, want logged %v", tt.name, line, tt.logged)
+		}
+	}
+}
+
+func TestLoggerRequestAndTraceIDs(t *testing.T) {
+	var out bytes.Buffer
+	e := New()
+	e.Use(LoggerWithConfig(LoggerConfig{Output: &out}), RequestID())
+	e.GET("/traced", func(c *Context) {
+		c.Set(TraceIDKey, "trace-1")
+	})
+	e.GET("/plain", func(c *Context) {})
+
+	performRequest(e, "GET", "/traced", nil, RequestIDHeader, "req-1")
+	line := out.String()
+	if !strings.Contains(line, "/traced") || !strings.Contains(line, "request_id=req-1") || !strings.Contains(line, "trace_id=trace-1") {
+		t.Errorf("logged %q, want the request and trace ids", line)
+	}
+
+	out.Reset()
+	w := performRequest(e, "GET", "/plain", nil)
+	if id := w.Header().Get(RequestIDHeader); id == "" || !strings.Contains(out.String(), "request_id="+id) {
+		t.Errorf("logged %q, want the generated id %q", out.String(), id)
+	}
+	if strings.Contains(out.String(), "trace_id=") {
+		t.Errorf("logged a trace id without tracing: %q", out.String())
+	}
+}
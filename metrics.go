@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// the key under which Metrics stores its registry
+	metricsKey = "metrics"
+
+	// weight of the latest request in the moving average latency
+	metricsAlpha = 0.1
+)
+
+type (
+	// RouteStats are the statistics collected by the Metrics middleware for a route
+	RouteStats struct {
+		// Count is the number of completed requests
+		Count int64 `json:"count"`
+
+		// AvgLatency is the exponential moving average of the request latency
+		AvgLatency time.Duration `json:"avg_latency"`
+	}
+
+	metricsRegistry struct {
+		mu     sync.Mutex
+		routes map[string]*RouteStats
+	}
+)
+
+func (r *metricsRegistry) record(route string, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats, ok := r.routes[route]
+	if !ok {
+		stats = &RouteStats{}
+		r.routes[route] = stats
+	}
+	stats.Count++
+	if stats.Count == 1 {
+		stats.AvgLatency = latency
+	} else {
+		stats.AvgLatency += time.Duration(metricsAlpha * float64(latency-stats.AvgLatency))
+	}
+}
+
+func (r *metricsRegistry) get(route string) RouteStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if stats, ok := r.routes[route]; ok {
+		return *stats
+	}
+	return RouteStats{}
+}
+
+// Metrics returns a middleware collecting the request count and latency of every route,
+// the handlers can read the ones of their own route with Context.RouteStats().
+func Metrics() HandlerFunc {
+	registry := &metricsRegistry{routes: map[string]*RouteStats{}}
+	return func(c *Context) {
+		c.Set(metricsKey, registry)
+		start := time.Now()
+		c.Next()
+		registry.record(c.Req.Method+" "+c.FullPath(), time.Since(start))
+	}
+}
+
+// Returns the statistics of the current route collected by the Metrics middleware so far,
+// the current request isn't counted yet. It returns zero stats without the middleware.
+func (c *Context) RouteStats() RouteStats {
+	registry, ok := c.Keys[metricsKey].(*metricsRegistry)
+	if !ok {
+		return RouteStats{}
+	}
+	return registry.get(c.Req.Method + " " + c.FullPath())
+}
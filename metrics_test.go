@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRouteStats(t *testing.T) {
+	var stats []RouteStats
+	e := New()
+	e.Use(Metrics())
+	e.GET("/items/:id", func(c *Context) {
+		stats = append(stats, c.RouteStats())
+		time.Sleep(time.Millisecond)
+	})
+	e.GET("/other", func(c *Context) {
+		stats = append(stats, c.RouteStats())
+	})
+
+	for _, path := range []string{"/items/1", "/items/2", "/items/3", "/other"} {
+		performRequest(e, "GET", path, nil)
+	}
+	if stats[0] != (RouteStats{}) {
+		t.Errorf("first request: stats = %+v, want zero", stats[0])
+	}
+	// the requests to the same route share their stats whatever the params
+	if stats[2].Count != 2 || stats[2].AvgLatency < time.Millisecond {
+		t.Errorf("third request: stats = %+v, want 2 requests of at least 1ms", stats[2])
+	}
+	if stats[3].Count != 0 {
+		t.Errorf("another route: stats = %+v, want zero", stats[3])
+	}
+}
+
+func TestRouteStatsWithoutMetrics(t *testing.T) {
+	var stats RouteStats
+	e := New()
+	e.GET("/", func(c *Context) {
+		stats = c.RouteStats()
+	})
+	performRequest(e, "GET", "/", nil)
+	if stats != (RouteStats{}) {
+		t.Errorf("stats = %+v, want zero", stats)
+	}
+}
@@ -0,0 +1,452 @@
+package engine
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// FieldNaming is the naming of the struct fields without a json tag in Context.JSON, see Engine.JSONFieldNaming
+type FieldNaming int
+
+const (
+	// the Go field names, as encoding/json does
+	DefaultNaming FieldNaming = iota
+
+	// the Go field names in snake_case, e.g. UserID is sent as user_id
+	SnakeCase
+)
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+type (
+	// jsonObject is a struct converted by renameFields, it keeps the order of the fields
+	jsonObject []jsonField
+
+	jsonField struct {
+		name  string
+		value interface{}
+	}
+)
+
+func (o jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, field := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		name, _ := json.Marshal(field.name)
+		buf.Write(name)
+		buf.WriteByte(':')
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+func (naming FieldNaming) rename(name string) string {
+	if naming == SnakeCase {
+		return snakeCase(name)
+	}
+	return name
+}
+
+// snakeCase converts a Go name into snake_case, keeping the initialisms together: HTTPServer is http_server.
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (!unicode.IsUpper(runes[i-1]) || i+1 < len(runes) && unicode.IsLower(runes[i+1])) {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// renameFields converts obj into values encoding/json serializes like obj, except that the struct fields
+// without a json tag are named by naming. The json tags, "-", omitempty and string, the embedded structs
+// and the marshalers are handled like encoding/json does, and so are the pointer cycles, reported as an error.
+func renameFields(obj interface{}, naming FieldNaming) (interface{}, error) {
+	if obj == nil {
+		return nil, nil
+	}
+	r := &renamer{naming: naming, path: map[cycleKey]bool{}}
+	return r.value(reflect.ValueOf(obj))
+}
+
+type (
+	// renamer converts a value for renameFields, path holds the references being converted to detect the cycles
+	renamer struct {
+		naming FieldNaming
+		path   map[cycleKey]bool
+	}
+
+	cycleKey struct {
+		typ reflect.Type
+		ptr uintptr
+		len int
+	}
+)
+
+func (r *renamer) value(v reflect.Value) (interface{}, error) {
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return v.Interface(), nil
+	}
+	if v.CanAddr() && (reflect.PtrTo(t).Implements(jsonMarshalerType) || reflect.PtrTo(t).Implements(textMarshalerType)) {
+		return v.Addr().Interface(), nil
+	}
+
+	switch v.Kind() {
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return r.value(v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		leave, err := r.enter(v, 0)
+		if err != nil {
+			return nil, err
+		}
+		defer leave()
+		return r.value(v.Elem())
+	case reflect.Struct:
+		return r.object(v)
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return r.mapping(v)
+	case reflect.Slice:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			// []byte is sent as base64
+			return v.Interface(), nil
+		}
+		if v.Len() > 0 {
+			leave, err := r.enter(v, v.Len())
+			if err != nil {
+				return nil, err
+			}
+			defer leave()
+		}
+		fallthrough
+	case reflect.Array:
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			item, err := r.value(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			list[i] = item
+		}
+		return list, nil
+	}
+	return v.Interface(), nil
+}
+
+// enter records the reference v while its content is converted, it fails if v is already being converted
+func (r *renamer) enter(v reflect.Value, length int) (func(), error) {
+	key := cycleKey{typ: v.Type(), ptr: v.Pointer(), len: length}
+	if r.path[key] {
+		return nil, &json.UnsupportedValueError{Value: v, Str: "encountered a cycle via " + v.Type().String()}
+	}
+	r.path[key] = true
+	return func() { delete(r.path, key) }, nil
+}
+
+func (r *renamer) object(v reflect.Value) (interface{}, error) {
+	fields := cachedFields(v.Type(), r.naming)
+	object := make(jsonObject, 0, len(fields))
+	for _, field := range fields {
+		fv, ok := fieldByIndex(v, field.index)
+		if !ok || field.omitEmpty && isEmptyJSONValue(fv) {
+			continue
+		}
+		var value interface{}
+		var err error
+		if field.quoted {
+			value, err = quoteValue(fv)
+		} else {
+			value, err = r.value(fv)
+		}
+		if err != nil {
+			return nil, err
+		}
+		object = append(object, jsonField{name: field.name, value: value})
+	}
+	return object, nil
+}
+
+// mapping converts a map, its keys are converted to strings like encoding/json does
+func (r *renamer) mapping(v reflect.Value) (interface{}, error) {
+	leave, err := r.enter(v, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer leave()
+	m := make(map[string]interface{}, v.Len())
+	for _, key := range v.MapKeys() {
+		var name string
+		switch {
+		case key.Kind() == reflect.String:
+			name = key.String()
+		case key.Type().Implements(textMarshalerType):
+			text, err := key.Interface().(encoding.TextMarshaler).MarshalText()
+			if err != nil {
+				return nil, err
+			}
+			name = string(text)
+		case key.Kind() >= reflect.Int && key.Kind() <= reflect.Int64:
+			name = strconv.FormatInt(key.Int(), 10)
+		case key.Kind() >= reflect.Uint && key.Kind() <= reflect.Uintptr:
+			name = strconv.FormatUint(key.Uint(), 10)
+		default:
+			// encoding/json reports the unsupported keys
+			return v.Interface(), nil
+		}
+		value, err := r.value(v.MapIndex(key))
+		if err != nil {
+			return nil, err
+		}
+		m[name] = value
+	}
+	return m, nil
+}
+
+// fieldByIndex returns the field of v at index, or false when it is in a nil embedded pointer
+func fieldByIndex(v reflect.Value, index []int) (reflect.Value, bool) {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v, true
+}
+
+// quoteValue encodes a value of a field with the string option, as a JSON string
+func quoteValue(v reflect.Value) (interface{}, error) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	raw, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	if v.Kind() != reflect.String {
+		return json.RawMessage(`"` + string(raw) + `"`), nil
+	}
+	raw, err = json.Marshal(string(raw))
+	return json.RawMessage(raw), err
+}
+
+type (
+	// namedField is a field of a struct serialized by renameFields, index leads to it through the embedded structs
+	namedField struct {
+		name      string
+		index     []int
+		tagged    bool
+		omitEmpty bool
+		quoted    bool
+	}
+
+	fieldsKey struct {
+		typ    reflect.Type
+		naming FieldNaming
+	}
+)
+
+var fieldsCache sync.Map
+
+func cachedFields(t reflect.Type, naming FieldNaming) []namedField {
+	key := fieldsKey{typ: t, naming: naming}
+	if fields, ok := fieldsCache.Load(key); ok {
+		return fields.([]namedField)
+	}
+	fields, _ := fieldsCache.LoadOrStore(key, structFields(t, naming))
+	return fields.([]namedField)
+}
+
+// structFields lists the serialized fields of t in their order, the fields of the embedded structs are promoted.
+// Like encoding/json, a name goes to the shallowest field, then to the tagged one,
+// and the fields left in conflict are dropped.
+func structFields(t reflect.Type, naming FieldNaming) []namedField {
+	type embedded struct {
+		typ   reflect.Type
+		index []int
+	}
+	var fields []namedField
+	taken := map[string]bool{}
+	visited := map[reflect.Type]bool{}
+	for level := []embedded{{typ: t}}; len(level) > 0; {
+		var next []embedded
+		count := map[reflect.Type]int{}
+		for _, e := range level {
+			count[e.typ]++
+		}
+		var names []string
+		candidates := map[string][]namedField{}
+		for _, e := range level {
+			if visited[e.typ] {
+				continue
+			}
+			visited[e.typ] = true
+			for i := 0; i < e.typ.NumField(); i++ {
+				sf := e.typ.Field(i)
+				ft := sf.Type
+				if sf.Anonymous {
+					if ft.Kind() == reflect.Ptr {
+						ft = ft.Elem()
+					}
+					if sf.PkgPath != "" && ft.Kind() != reflect.Struct {
+						continue
+					}
+				} else if sf.PkgPath != "" {
+					continue
+				}
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts := tag, ""
+				if comma := strings.Index(tag, ","); comma >= 0 {
+					name, opts = tag[:comma], tag[comma:]
+				}
+				index := append(append([]int(nil), e.index...), i)
+				if sf.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+					next = append(next, embedded{typ: ft, index: index})
+					continue
+				}
+				field := namedField{
+					name:      name,
+					index:     index,
+					tagged:    name != "",
+					omitEmpty: strings.Contains(opts, ",omitempty"),
+					quoted:    strings.Contains(opts, ",string") && isQuotable(sf.Type),
+				}
+				if name == "" {
+					field.name = naming.rename(sf.Name)
+				}
+				if candidates[field.name] == nil {
+					names = append(names, field.name)
+				}
+				candidates[field.name] = append(candidates[field.name], field)
+				if count[e.typ] > 1 {
+					// the same struct embedded twice at this depth, its fields conflict
+					candidates[field.name] = append(candidates[field.name], field)
+				}
+			}
+		}
+		for _, name := range names {
+			if taken[name] {
+				continue
+			}
+			taken[name] = true
+			if field, ok := dominantField(candidates[name]); ok {
+				fields = append(fields, field)
+			}
+		}
+		level = next
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		a, b := fields[i].index, fields[j].index
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return fields
+}
+
+// dominantField returns the field getting a name among the fields of the same depth
+func dominantField(fields []namedField) (namedField, bool) {
+	if len(fields) == 1 {
+		return fields[0], true
+	}
+	var tagged []namedField
+	for _, field := range fields {
+		if field.tagged {
+			tagged = append(tagged, field)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return namedField{}, false
+}
+
+// isQuotable reports whether the string option applies to the type, as with encoding/json
+func isQuotable(t reflect.Type) bool {
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return false
+	}
+	if t.Name() == "" && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	}
+	return false
+}
+
+// isEmptyJSONValue reports whether omitempty drops the value, like encoding/json
+func isEmptyJSONValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return v.Len() == 0
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return v.IsNil()
+	}
+	return false
+}
+
+// nameFields applies Engine.JSONFieldNaming to obj
+func (c *Context) nameFields(obj interface{}) (interface{}, error) {
+	if c.engine == nil || c.engine.JSONFieldNaming == DefaultNaming {
+		return obj, nil
+	}
+	return renameFields(obj, c.engine.JSONFieldNaming)
+}
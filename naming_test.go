@@ -0,0 +1,73 @@
+package engine
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"Name":       "name",
+		"UserID":     "user_id",
+		"HTTPServer": "http_server",
+		"CreatedAt":  "created_at",
+		"A":          "a",
+	}
+	for name, want := range tests {
+		if got := snakeCase(name); got != want {
+			t.Errorf("snakeCase(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func renderNamed(obj interface{}) (int, string) {
+	e := New()
+	e.JSONFieldNaming = SnakeCase
+	e.GET("/", func(c *Context) {
+		c.JSON(200, obj)
+	})
+	w := performRequest(e, "GET", "/", nil)
+	return w.Code, strings.TrimSpace(w.Body.String())
+}
+
+func TestJSONFieldNaming(t *testing.T) {
+	type Audit struct {
+		CreatedBy string
+		UpdatedBy string
+	}
+	type user struct {
+		UserID  int
+		Name    string `json:"display"`
+		Count   int    `json:",string"`
+		Skipped string `json:"-"`
+		Email   string `json:",omitempty"`
+		Audit
+		// shallower than Audit.UpdatedBy, it takes the name
+		UpdatedBy string
+	}
+	code, body := renderNamed(user{UserID: 1, Name: "ann", Count: 3, Skipped: "x",
+		Audit: Audit{CreatedBy: "bob", UpdatedBy: "deep"}, UpdatedBy: "top"})
+	want := `{"user_id":1,"display":"ann","count":"3","created_by":"bob","updated_by":"top"}`
+	if code != 200 || body != want {
+		t.Errorf("got %d %s, want 200 %s", code, body, want)
+	}
+
+	// the maps and the marshalers are kept as is
+	_, body = renderNamed(H{"FirstName": json.RawMessage(`{"KeepCase":true}`)})
+	if body != `{"FirstName":{"KeepCase":true}}` {
+		t.Errorf("map body = %s", body)
+	}
+}
+
+func TestJSONFieldNamingCycle(t *testing.T) {
+	type node struct {
+		Name string
+		Next *node
+	}
+	n := &node{Name: "loop"}
+	n.Next = n
+	if code, _ := renderNamed(n); code != 500 {
+		t.Errorf("status = %d, want 500 for a cycle", code)
+	}
+}
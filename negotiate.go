@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	MIMEJSON = "application/json"
+	MIMEXML  = "application/xml"
+	MIMEHTML = "text/html"
+)
+
+// Negotiate describes the formats offered by Context.Negotiate
+type Negotiate struct {
+	// Offered lists the offered media types in order of preference
+	Offered []string
+
+	// Data is rendered for the JSON and XML formats, and passed to the HTML template
+	Data interface{}
+
+	// HTMLName is the template rendered for text/html
+	HTMLName string
+
+	// Renderers take over the response of their media type and set the status and body themselves,
+	// e.g. to answer an HTML error page and a JSON error with different bodies.
+	Renderers map[string]HandlerFunc
+}
+
+// Negotiate renders the offered format preferred by the Accept header of the request.
+// The format is rendered by its renderer if there is one, otherwise the data is rendered with code
+// for JSON, XML and HTML. It answers with a 406 when none of the offered formats is acceptable.
+func (c *Context) Negotiate(code int, config Negotiate) {
+	format := c.NegotiateFormat(config.Offered...)
+	if renderer, ok := config.Renderers[format]; ok {
+		renderer(c)
+		return
+	}
+	switch format {
+	case MIMEJSON:
+		c.JSON(code, config.Data)
+	case MIMEXML:
+		c.XML(code, config.Data)
+	case MIMEHTML:
+		c.HTML(code, config.HTMLName, config.Data)
+	default:
+		c.Fail(http.StatusNotAcceptable, errors.New("the accepted formats are not offered by the server"))
+	}
+}
+
+// Returns the offered media type preferred by the Accept header, the first offered one when the request
+// has no Accept header, and an empty string when none is acceptable. Accept is added to the Vary header.
+func (c *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	c.AddVary("Accept")
+	accepted := parseAccept(c.Req.Header.Get("Accept"))
+	if len(accepted) == 0 {
+		return offered[0]
+	}
+	for _, a := range accepted {
+		for _, o := range offered {
+			if mediaTypeMatches(a, o) {
+				return o
+			}
+		}
+	}
+	return ""
+}
+
+// AddVary adds field to the Vary header of the response unless it is already listed,
+// so the middleware can each add the request headers the response depends on.
+func (c *Context) AddVary(field string) {
+	header := c.Writer.Header()
+	var fields []string
+	for _, value := range header["Vary"] {
+		for _, f := range strings.Split(value, ",") {
+			f = strings.TrimSpace(f)
+			if f == "*" || strings.EqualFold(f, field) {
+				return
+			}
+			if f != "" {
+				fields = append(fields, f)
+			}
+		}
+	}
+	header.Set("Vary", strings.Join(append(fields, field), ", "))
+}
+
+// parseAccept returns the acceptable media types sorted by quality, the types with q=0 are dropped
+func parseAccept(header string) []string {
+	type accept struct {
+		mediaType string
+		q         float64
+	}
+	var list []accept
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+		if mediaType == "" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if f, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q > 0 {
+			list = append(list, accept{mediaType, q})
+		}
+	}
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].q > list[j].q
+	})
+	types := make([]string, len(list))
+	for i, a := range list {
+		types[i] = a.mediaType
+	}
+	return types
+}
+
+// mediaTypeMatches reports whether the offered type matches the accepted one, which may contain wildcards
+func mediaTypeMatches(accepted, offered string) bool {
+	offered = strings.ToLower(offered)
+	if accepted == "*/*" || accepted == offered {
+		return true
+	}
+	if strings.HasSuffix(accepted, "/*") {
+		return strings.HasPrefix(offered, accepted[:len(accepted)-1])
+	}
+	return false
+}
@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"compress/gzip"
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestNegotiateRenderers(t *testing.T) {
+	e := New()
+	e.HTMLTemplates = template.Must(template.New("error").Parse(`<h1>{{.}}</h1>`))
+	e.GET("/item", func(c *Context) {
+		c.Negotiate(200, Negotiate{
+			Offered: []string{MIMEJSON, MIMEHTML},
+			Renderers: map[string]HandlerFunc{
+				MIMEHTML: func(c *Context) {
+					c.HTML(404, "error", "no such item")
+				},
+				MIMEJSON: func(c *Context) {
+					c.JSON(410, H{"error": "gone"})
+				},
+			},
+		})
+	})
+
+	w := performRequest(e, "GET", "/item", nil, "Accept", "text/html")
+	if w.Code != 404 || w.Body.String() != "<h1>no such item</h1>" {
+		t.Errorf("html: got %d %q", w.Code, w.Body.String())
+	}
+	w = performRequest(e, "GET", "/item", nil, "Accept", "application/json")
+	if w.Code != 410 || strings.TrimSpace(w.Body.String()) != `{"error":"gone"}` {
+		t.Errorf("json: got %d %q", w.Code, w.Body.String())
+	}
+	if vary := w.Header().Get("Vary"); vary != "Accept" {
+		t.Errorf("Vary = %q, want Accept", vary)
+	}
+	if w := performRequest(e, "GET", "/item", nil, "Accept", "image/png"); w.Code != 406 {
+		t.Errorf("unacceptable: status = %d, want 406", w.Code)
+	}
+}
+
+func TestNegotiateData(t *testing.T) {
+	e := New()
+	e.GET("/item", func(c *Context) {
+		c.Negotiate(200, Negotiate{Offered: []string{MIMEJSON, MIMEXML}, Data: H{"id": 1}})
+	})
+	w := performRequest(e, "GET", "/item", nil, "Accept", "text/html;q=0.9, */*;q=0.1")
+	if strings.TrimSpace(w.Body.String()) != `{"id":1}` {
+		t.Errorf("got %q, want the JSON data", w.Body.String())
+	}
+}
+
+func TestAddVaryCombinesFields(t *testing.T) {
+	e := New()
+	e.Use(Gzip(gzip.DefaultCompression))
+	e.GET("/", func(c *Context) {
+		c.AddVary("accept-encoding")
+		c.Negotiate(200, Negotiate{Offered: []string{MIMEJSON}, Data: H{"ok": true}})
+	})
+	e.GET("/preset", func(c *Context) {
+		c.Writer.Header().Set("Vary", "Origin")
+		c.AddVary("Accept")
+		c.AddVary("Origin")
+	})
+
+	w := performRequest(e, "GET", "/", nil, "Accept-Encoding", "gzip")
+	if vary := w.Header()["Vary"]; len(vary) != 1 || vary[0] != "Accept-Encoding, Accept" {
+		t.Errorf("Vary = %q, want a single Accept-Encoding, Accept", vary)
+	}
+	if vary := performRequest(e, "GET", "/preset", nil).Header().Get("Vary"); vary != "Origin, Accept" {
+		t.Errorf("Vary = %q, want the preset field kept", vary)
+	}
+}
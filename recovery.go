@@ -93,3 +93,67 @@ func Recovery() HandlerFunc {
 		c.Next()
 	}
 }
+
+// Go runs fn in a new goroutine with a copy of the context, see Context.Copy().
+// A panic in fn is recovered and logged the same way Recovery does instead of crashing the process.
+func (c *Context) Go(fn func(*Context)) {
+	cp := c.Copy()
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				stack := stack(3)
+				log.Printf("PANIC in goroutine: %s\n%s", err, stack)
+			}
+		}()
+		fn(cp)
+	}()
+}
+
+// templatePanic is the error of a template that panicked during its execution
+type templatePanic struct {
+	name  string
+	value interface{}
+}
+
+func (p templatePanic) Error() string {
+	return fmt.Sprintf("template %s panicked: %v", p.name, p.value)
+}
+
+// executeTemplate renders the html template in a buffer, a panic is logged like Recovery does
+// and returned as a templatePanic error.
+func (c *Context) executeTemplate(name string, data interface{}) (body []byte, err error) {
+	defer func() {
+		if value := recover(); value != nil {
+			stack := stack(3)
+			log.Printf("PANIC: %s\n%s", value, stack)
+			body, err = nil, templatePanic{name: name, value: value}
+		}
+	}()
+	var buf bytes.Buffer
+	if err := c.engine.HTMLTemplates.ExecuteTemplate(&buf, name, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// handlePanic is the httprouter PanicHandler, it handles the panics escaping the middleware chain
+// (e.g. when there is no Recovery middleware) the same way Recovery does.
+// The 500 is only written if the response didn't start.
+func (engine *Engine) handlePanic(w http.ResponseWriter, req *http.Request, err interface{}) {
+	stack := stack(3)
+	log.Printf("PANIC: %s\n%s", err, stack)
+	if rw, ok := w.(ResponseWriter); ok && rw.HeaderWritten() {
+		return
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+}
+
+// catchPanic passes a panic of the handlers to handlePanic with the writer of the context,
+// which knows whether the response already started, unlike the one httprouter has.
+// The OnResponseDone callbacks then run with the 500.
+func (c *Context) catchPanic() {
+	if err := recover(); err != nil {
+		c.engine.handlePanic(c.Writer, c.Req, err)
+		c.responseDone()
+	}
+}
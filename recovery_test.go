@@ -0,0 +1,110 @@
+package engine
+
+import (
+	"html/template"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// logLines receives every write made to the standard logger
+type logLines chan string
+
+func (l logLines) Write(p []byte) (int, error) {
+	l <- string(p)
+	return len(p), nil
+}
+
+func TestGoRecoversPanics(t *testing.T) {
+	lines := make(logLines, 1)
+	log.SetOutput(lines)
+	defer log.SetOutput(os.Stderr)
+
+	got := make(chan interface{}, 1)
+	e := New()
+	e.GET("/", func(c *Context) {
+		c.Set("user", "ann")
+		c.Go(func(c *Context) {
+			got <- c.Get("user")
+			panic("boom")
+		})
+		c.String(200, "ok")
+	})
+
+	w := performRequest(e, "GET", "/", nil)
+	if w.Body.String() != "ok" {
+		t.Errorf("body = %q, want ok", w.Body.String())
+	}
+	if user := <-got; user != "ann" {
+		t.Errorf("the goroutine got user %v, want ann", user)
+	}
+	select {
+	case line := <-lines:
+		if !strings.Contains(line, "PANIC in goroutine: boom") {
+			t.Errorf("logged %q", line)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the panic wasn't logged")
+	}
+
+	// still serving after the panic
+	if w := performRequest(e, "GET", "/", nil); w.Code != 200 {
+		t.Errorf("status = %d, want 200", w.Code)
+	}
+	<-got
+	<-lines
+}
+
+func TestPanicWithoutRecovery(t *testing.T) {
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	e := New()
+	e.GET("/panic", func(c *Context) {
+		panic("boom")
+	})
+	e.GET("/late", func(c *Context) {
+		c.String(201, "partial")
+		panic("boom")
+	})
+
+	w := performRequest(e, "GET", "/panic", nil)
+	if w.Code != 500 {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	w = performRequest(e, "GET", "/late", nil)
+	if w.Code != 201 || w.Body.String() != "partial" {
+		t.Errorf("a panic after the response started: got %d %q, want the 201 kept", w.Code, w.Body.String())
+	}
+}
+
+func TestHTMLTemplatePanic(t *testing.T) {
+	var errs ErrorMsgs
+	e := New()
+	e.HTMLTemplates = template.Must(template.New("page").Funcs(template.FuncMap{
+		"owner": func(m map[string]string) string {
+			panic("nil map " + m["x"])
+		},
+	}).Parse(`<h1>{{.title}}</h1><p>{{owner .meta}}</p>`))
+	e.GET("/", func(c *Context) {
+		c.HTML(200, "page", H{"title": "partial"})
+		errs = c.Errors
+	})
+
+	w := performRequest(e, "GET", "/", nil)
+	if w.Code != 500 {
+		t.Errorf("status = %d, want 500", w.Code)
+	}
+	if body := w.Body.String(); strings.Contains(body, "partial") || strings.Contains(body, "nil map") {
+		t.Errorf("body = %q, the partial output or the panic leaked", body)
+	}
+	if strings.Contains(w.Header().Get("Content-Type"), "html") {
+		t.Errorf("Content-Type = %q, want the one of the plain 500", w.Header().Get("Content-Type"))
+	}
+	if len(errs) != 1 || !strings.Contains(errs[0].Err, "nil map") {
+		t.Errorf("c.Errors = %v, want the panic", errs)
+	}
+}
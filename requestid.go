@@ -0,0 +1,115 @@
+package engine
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"time"
+)
+
+const (
+	// the default header used to carry the request id
+	RequestIDHeader = "X-Request-ID"
+
+	// the key under which the request id is stored in Context.Keys
+	RequestIDKey = "requestID"
+)
+
+type (
+	// IDGenerator produces the ids attached to incoming requests
+	IDGenerator interface {
+		NewID() string
+	}
+
+	// IDGeneratorFunc is an adapter to allow the use of ordinary functions as IDGenerator
+	IDGeneratorFunc func() string
+
+	// RequestIDConfig configures the RequestIDWithConfig middleware
+	RequestIDConfig struct {
+		// Header is the name of the request/response header carrying the id, X-Request-ID by default
+		Header string
+
+		// Generator creates a new id when the request doesn't carry one, UUIDv4 by default
+		Generator IDGenerator
+	}
+)
+
+func (f IDGeneratorFunc) NewID() string {
+	return f()
+}
+
+// UUIDv4 returns a generator of random (version 4) UUIDs
+func UUIDv4() IDGenerator {
+	return IDGeneratorFunc(func() string {
+		var b [16]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			panic(err)
+		}
+		b[6] = (b[6] & 0x0f) | 0x40
+		b[8] = (b[8] & 0x3f) | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	})
+}
+
+// crockford's base32 alphabet used by ULIDs
+const ulidAlphabet = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID returns a generator of lexicographically sortable ids (https://github.com/ulid/spec).
+// Ids generated in the same millisecond are not guaranteed to be monotonic.
+func ULID() IDGenerator {
+	return IDGeneratorFunc(func() string {
+		var b [16]byte
+		binary.BigEndian.PutUint64(b[:8], uint64(time.Now().UnixNano()/int64(time.Millisecond))<<16)
+		if _, err := rand.Read(b[6:]); err != nil {
+			panic(err)
+		}
+		return encodeULID(b)
+	})
+}
+
+// encodeULID encodes the 128 bits of b as 26 base32 characters
+func encodeULID(b [16]byte) string {
+	hi := binary.BigEndian.Uint64(b[:8])
+	lo := binary.BigEndian.Uint64(b[8:])
+	out := make([]byte, 26)
+	for i := 25; i >= 0; i-- {
+		out[i] = ulidAlphabet[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out)
+}
+
+// RequestID returns a middleware that tags every request with a X-Request-ID header
+func RequestID() HandlerFunc {
+	return RequestIDWithConfig(RequestIDConfig{})
+}
+
+// RequestIDWithConfig returns a RequestID middleware with the given header name and generator.
+// An id sent by the client is reused, otherwise a new one is generated.
+// The id is echoed in the response header and stored in the context under RequestIDKey.
+func RequestIDWithConfig(config RequestIDConfig) HandlerFunc {
+	if config.Header == "" {
+		config.Header = RequestIDHeader
+	}
+	if config.Generator == nil {
+		config.Generator = UUIDv4()
+	}
+	return func(c *Context) {
+		id := c.Req.Header.Get(config.Header)
+		if id == "" {
+			id = config.Generator.NewID()
+		}
+		c.Set(RequestIDKey, id)
+		c.Writer.Header().Set(config.Header, id)
+		c.Next()
+	}
+}
+
+// Returns the id assigned by the RequestID middleware, or an empty string.
+func (c *Context) RequestID() string {
+	if id, ok := c.Keys[RequestIDKey].(string); ok {
+		return id
+	}
+	return ""
+}
@@ -0,0 +1,50 @@
+package engine
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRequestIDCustomHeader(t *testing.T) {
+	e := New()
+	e.Use(RequestIDWithConfig(RequestIDConfig{Header: "X-Trace"}))
+	var id string
+	e.GET("/", func(c *Context) {
+		id = c.RequestID()
+	})
+
+	w := performRequest(e, "GET", "/", nil)
+	if id == "" {
+		t.Fatal("no request id was generated")
+	}
+	if got := w.Header().Get("X-Trace"); got != id {
+		t.Errorf("X-Trace header = %q, want %q", got, id)
+	}
+	if got := w.Header().Get(RequestIDHeader); got != "" {
+		t.Errorf("unexpected %s header %q", RequestIDHeader, got)
+	}
+
+	w = performRequest(e, "GET", "/", nil, "X-Trace", "from-client")
+	if id != "from-client" || w.Header().Get("X-Trace") != "from-client" {
+		t.Errorf("the client id wasn't reused: got %q", id)
+	}
+}
+
+func TestRequestIDDeterministicGenerator(t *testing.T) {
+	n := 0
+	e := New()
+	e.Use(RequestIDWithConfig(RequestIDConfig{Generator: IDGeneratorFunc(func() string {
+		n++
+		return fmt.Sprintf("id-%d", n)
+	})}))
+	e.GET("/", func(c *Context) {
+		c.String(200, c.RequestID())
+	})
+
+	for _, want := range []string{"id-1", "id-2"} {
+		w := performRequest(e, "GET", "/", nil)
+		if w.Body.String() != want || w.Header().Get(RequestIDHeader) != want {
+			t.Errorf("got id %q and header %q, want %q", w.Body.String(), w.Header().Get(RequestIDHeader), want)
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package engine
+
+import (
+	"net/http"
+)
+
+type (
+	// ResponseWriter is the http.ResponseWriter used by the Context, it also keeps track of the response status
+	ResponseWriter interface {
+		http.ResponseWriter
+		http.Flusher
+
+		// Returns the HTTP response status code of the current request, 0 until a status or a body is written.
+		Status() int
+
+		// Returns whether or not the response status was already written, explicitly by WriteHeader or implicitly by Write.
+		// A response with only a status, e.g. a 204, is Written(), use Size() to know whether a body was written.
+		Written() bool
+
+		// Returns the number of bytes of the body written so far.
+		Size() int
+
+		// Returns whether or not the response header was sent to the client, explicitly by WriteHeader
+		// or implicitly by Write, after which setting headers has no effect. A buffered response keeps its header
+		// until it is sent, so it can have a Status() and body bytes while its header isn't written yet.
+		HeaderWritten() bool
+	}
+
+	responseWriter struct {
+		http.ResponseWriter
+		status      int
+		size        int
+		wroteHeader bool
+	}
+
+	// statusTextWriter watches whether a body is written after Context.Status()
+	statusTextWriter struct {
+		ResponseWriter
+		code      int
+		wroteBody bool
+	}
+)
+
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.status = 0
+	w.size = 0
+	w.wroteHeader = false
+}
+
+// WriteHeader does nothing once the header was sent, the first status is kept
+func (w *responseWriter) WriteHeader(s int) {
+	if w.wroteHeader {
+		return
+	}
+	w.ResponseWriter.WriteHeader(s)
+	w.status = s
+	w.wroteHeader = true
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	// the first write sends the header with a 200
+	w.headerSent()
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+// headerSent records the implicit 200 sent by net/http when writing without WriteHeader
+func (w *responseWriter) headerSent() {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		w.status = http.StatusOK
+	}
+}
+
+func (w *responseWriter) Written() bool {
+	return w.wroteHeader
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) HeaderWritten() bool {
+	return w.wroteHeader
+}
+
+// Flush sends the buffered data to the client if the underlying writer supports it
+func (w *responseWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		w.headerSent()
+		flusher.Flush()
+	}
+}
+
+func (w *statusTextWriter) Write(data []byte) (int, error) {
+	if len(data) > 0 {
+		w.wroteBody = true
+	}
+	return w.ResponseWriter.Write(data)
+}
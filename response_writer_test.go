@@ -0,0 +1,60 @@
+package engine
+
+import "testing"
+
+func TestHeaderWritten(t *testing.T) {
+	type state struct {
+		status                 int
+		written, headerWritten bool
+	}
+	var states []state
+	record := func(c *Context) {
+		states = append(states, state{c.Writer.Status(), c.Writer.Written(), c.Writer.HeaderWritten()})
+	}
+	e := New()
+	e.GET("/", func(c *Context) {
+		record(c)
+		c.Writer.WriteHeader(204)
+		record(c)
+	})
+	e.GET("/body", func(c *Context) {
+		c.Writer.Write([]byte("x"))
+		record(c)
+	})
+
+	performRequest(e, "GET", "/", nil)
+	performRequest(e, "GET", "/body", nil)
+	want := []state{
+		{0, false, false},
+		// a status only: the header is sent, Size() tells that no body was written
+		{204, true, true},
+		// the first write sends an implicit 200
+		{200, true, true},
+	}
+	for i := range want {
+		if states[i] != want[i] {
+			t.Errorf("state %d = %+v, want %+v", i, states[i], want[i])
+		}
+	}
+}
+
+func TestResponseWriterSizeAndStatus(t *testing.T) {
+	var status, size int
+	e := New()
+	e.GET("/", func(c *Context) {
+		c.Abort(401)
+		c.Writer.Write([]byte("denied"))
+		c.Writer.Write([]byte(", "))
+		c.JSON(500, H{"error": "late"})
+		status, size = c.Writer.Status(), c.Writer.Size()
+	})
+
+	w := performRequest(e, "GET", "/", nil)
+	body := "denied, {\"error\":\"late\"}\n"
+	if w.Code != 401 || status != 401 {
+		t.Errorf("status: recorded %d, sent %d, want the first 401", status, w.Code)
+	}
+	if size != len(body) || w.Body.String() != body {
+		t.Errorf("size = %d for the body %q, want %d", size, w.Body.String(), len(body))
+	}
+}
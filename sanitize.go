@@ -0,0 +1,74 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Sanitizer normalizes the string fields after binding, field is the Go name of the struct field
+type Sanitizer = func(field string, value string) string
+
+// SetSanitizer registers a function applied to every bound string field, after the `sanitize` tags.
+func (engine *Engine) SetSanitizer(sanitizer Sanitizer) {
+	engine.sanitizer = sanitizer
+}
+
+// finishBinding sanitizes then validates a freshly decoded item, every binder ends with it
+func finishBinding(c *Context, item interface{}) error {
+	var sanitizer Sanitizer
+	if c != nil && c.engine != nil {
+		sanitizer = c.engine.sanitizer
+	}
+	sanitize(reflect.ValueOf(item), sanitizer)
+	return Validate(c, item)
+}
+
+// sanitize applies the `sanitize:"trim,lower"` tags and the sanitizer to the string fields of v.
+// The supported operations are trim, lower and upper.
+func sanitize(v reflect.Value, sanitizer Sanitizer) {
+	v = indirectValue(v)
+	if !v.IsValid() || v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		ops := strings.Split(field.Tag.Get("sanitize"), ",")
+		clean := func(s string) string {
+			for _, op := range ops {
+				switch strings.TrimSpace(op) {
+				case "trim":
+					s = strings.TrimSpace(s)
+				case "lower":
+					s = strings.ToLower(s)
+				case "upper":
+					s = strings.ToUpper(s)
+				}
+			}
+			if sanitizer != nil {
+				s = sanitizer(field.Name, s)
+			}
+			return s
+		}
+
+		fieldValue := indirectValue(v.Field(i))
+		if !fieldValue.IsValid() {
+			continue
+		}
+		switch fieldValue.Kind() {
+		case reflect.String:
+			fieldValue.SetString(clean(fieldValue.String()))
+		case reflect.Slice:
+			if fieldValue.Type().Elem().Kind() == reflect.String {
+				for j := 0; j < fieldValue.Len(); j++ {
+					fieldValue.Index(j).SetString(clean(fieldValue.Index(j).String()))
+				}
+			}
+		case reflect.Struct:
+			sanitize(fieldValue, sanitizer)
+		}
+	}
+}
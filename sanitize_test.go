@@ -0,0 +1,52 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSanitizeTags(t *testing.T) {
+	type signup struct {
+		Email string   `json:"email" sanitize:"trim,lower" binding:"required"`
+		Name  string   `json:"name" sanitize:"trim"`
+		Tags  []string `json:"tags" sanitize:"trim,upper"`
+		Bio   string   `json:"bio"`
+	}
+	var got signup
+	err := bindJSON(New(), `{"email":"  Ann@Example.COM ","name":"\tAnn ","tags":[" a ","b "],"bio":" as is "}`, &got)
+	if err != nil {
+		t.Fatalf("ParseBody returned %v", err)
+	}
+	want := signup{Email: "ann@example.com", Name: "Ann", Tags: []string{"A", "B"}, Bio: " as is "}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// sanitized before the validation
+	var blank signup
+	if err := bindJSON(New(), `{"email":"   "}`, &blank); err == nil {
+		t.Error("a blank email passed the required rule")
+	}
+}
+
+func TestSetSanitizer(t *testing.T) {
+	type comment struct {
+		Author string `json:"author" sanitize:"trim"`
+		Text   string `json:"text"`
+	}
+	e := New()
+	e.SetSanitizer(func(field, value string) string {
+		if field == "Text" {
+			return strings.Replace(value, "<", "&lt;", -1)
+		}
+		return value
+	})
+	var got comment
+	if err := bindJSON(e, `{"author":" ann ","text":"<b>hi"}`, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Author != "ann" || got.Text != "&lt;b>hi" {
+		t.Errorf("got %+v", got)
+	}
+}
@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"strings"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// SingleFlight returns a middleware that runs the handlers only once for identical concurrent GET and HEAD requests.
+// Requests returning the same key while a first one is in flight wait for it and receive a copy of its response.
+// When keyFunc is nil the key is the method, the request URI and the Authorization and Cookie headers,
+// so the users only share the responses of their own requests. A keyFunc must include the identity of the user
+// as well when the responses depend on it.
+func SingleFlight(keyFunc func(*Context) string) HandlerFunc {
+	if keyFunc == nil {
+		keyFunc = func(c *Context) string {
+			header := c.Req.Header
+			return c.Req.Method + " " + c.Req.URL.RequestURI() +
+				"\n" + strings.Join(header["Authorization"], "\n") + "\n" + strings.Join(header["Cookie"], "; ")
+		}
+	}
+	var group singleflight.Group
+	return func(c *Context) {
+		if c.Req.Method != "GET" && c.Req.Method != "HEAD" {
+			c.Next()
+			return
+		}
+
+		v, _, _ := group.Do(keyFunc(c), func() (interface{}, error) {
+			w := newBufferWriter(c.Writer)
+			c.Writer = w
+			defer func() {
+				c.Writer = w.ResponseWriter
+			}()
+			c.Next()
+			c.writeStatusText()
+			return w.response(), nil
+		})
+		v.(*recordedResponse).writeTo(c.Writer)
+		// the response has been built by the first request, skip the pending handlers of the others
+		c.index = AbortIndex
+	}
+}
@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestSingleFlightCoalescesConcurrentGETs(t *testing.T) {
+	const n = 5
+	var calls, arrived int32
+	all := make(chan struct{})
+	e := New()
+	// the handler waits for every request to reach SingleFlight, so they all join the first one
+	e.Use(func(c *Context) {
+		if atomic.AddInt32(&arrived, 1) == n {
+			close(all)
+		}
+		c.Next()
+	}, SingleFlight(nil))
+	e.GET("/report", func(c *Context) {
+		atomic.AddInt32(&calls, 1)
+		<-all
+		c.String(200, "report")
+	})
+
+	var wg sync.WaitGroup
+	bodies := make([]string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bodies[i] = performRequest(e, "GET", "/report", nil).Body.String()
+		}(i)
+	}
+	wg.Wait()
+
+	if calls != 1 {
+		t.Errorf("the handler ran %d times, want 1", calls)
+	}
+	for i, body := range bodies {
+		if body != "report" {
+			t.Errorf("request %d got %q", i, body)
+		}
+	}
+}
+
+func TestSingleFlightKeepsUsersApart(t *testing.T) {
+	const n = 6
+	var calls, arrived int32
+	all := make(chan struct{})
+	e := New()
+	e.Use(func(c *Context) {
+		if atomic.AddInt32(&arrived, 1) == n {
+			close(all)
+		}
+		c.Next()
+	}, SingleFlight(nil))
+	e.GET("/me", func(c *Context) {
+		atomic.AddInt32(&calls, 1)
+		<-all
+		c.String(200, c.Req.Header.Get("Cookie")+c.Req.Header.Get("Authorization"))
+	})
+
+	users := [][]string{{"Cookie", "session=ann"}, {"Cookie", "session=bob"}, {"Authorization", "Bearer eve"}}
+	var wg sync.WaitGroup
+	bodies := make([]string, n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			bodies[i] = performRequest(e, "GET", "/me", nil, users[i%len(users)]...).Body.String()
+		}(i)
+	}
+	wg.Wait()
+
+	if calls != int32(len(users)) {
+		t.Errorf("the handler ran %d times, want once per user", calls)
+	}
+	for i, body := range bodies {
+		if want := users[i%len(users)][1]; body != want {
+			t.Errorf("request %d of %s got %q", i, want, body)
+		}
+	}
+}
+
+func TestSingleFlightSkipsUnsafeMethods(t *testing.T) {
+	calls := 0
+	e := New()
+	e.Use(SingleFlight(nil))
+	e.POST("/", func(c *Context) {
+		calls++
+	})
+	performRequest(e, "POST", "/", nil)
+	performRequest(e, "POST", "/", nil)
+	if calls != 2 {
+		t.Errorf("the POST handler ran %d times, want 2", calls)
+	}
+}
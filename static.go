@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// StaticFile registers a single route in order to serve a single file of the local filesystem.
+// router.StaticFile("favicon.ico", "./resources/favicon.ico")
+// A missing file is answered by the 404 handlers of the engine.
+func (group *RouterGroup) StaticFile(relativePath, filepath string) {
+	if strings.ContainsAny(relativePath, ":*") {
+		panic("URL parameters can not be used when serving a static file")
+	}
+	handler := func(c *Context) {
+		f, err := os.Open(filepath)
+		if err != nil {
+			c.notFound()
+			return
+		}
+		defer f.Close()
+		serveStaticFile(c, f)
+	}
+	group.Handle("GET", relativePath, []HandlerFunc{handler})
+	group.Handle("HEAD", relativePath, []HandlerFunc{handler})
+}
+
+// Static serves the files of the root directory under relativePath, e.g.
+// router.Static("/assets", "./public") serves ./public/css/app.css at /assets/css/app.css.
+// The paths can't escape root, the directories are served by their index.html and are never listed,
+// and the missing files are answered by the 404 handlers of the engine.
+func (group *RouterGroup) Static(relativePath, root string) {
+	if strings.ContainsAny(relativePath, ":*") {
+		panic("URL parameters can not be used when serving a static folder")
+	}
+	fs := http.Dir(root)
+	handler := func(c *Context) {
+		// cleaning a rooted path drops the ../ segments
+		name := path.Clean("/" + c.Params.ByName("filepath"))
+		f, err := fs.Open(name)
+		if err != nil {
+			c.notFound()
+			return
+		}
+		defer f.Close()
+		if stat, err := f.Stat(); err == nil && stat.IsDir() {
+			index, err := fs.Open(path.Join(name, "index.html"))
+			if err != nil {
+				c.notFound()
+				return
+			}
+			defer index.Close()
+			f = index
+		}
+		serveStaticFile(c, f)
+	}
+	p := path.Join(relativePath, "/*filepath")
+	group.Handle("GET", p, []HandlerFunc{handler})
+	group.Handle("HEAD", p, []HandlerFunc{handler})
+}
+
+// serveStaticFile writes the content of an opened file, directories are answered with a 404
+func serveStaticFile(c *Context, f http.File) {
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		c.notFound()
+		return
+	}
+	http.ServeContent(c.Writer, c.Req, stat.Name(), stat.ModTime(), f)
+}
+
+// ServeFileWithCache serves a file of the local filesystem with an ETag made of its modification time and size,
+// so the conditional requests (If-None-Match, If-Modified-Since) are answered with a 304 Not Modified
+// and the Range requests with a 206 Partial Content, letting the downloads resume.
+// The Content-Type is guessed from the extension of name, the file name by default.
+// A missing file is answered by the 404 handlers of the engine.
+func (c *Context) ServeFileWithCache(name, filepath string) {
+	if c.requestDone() {
+		return
+	}
+	f, err := os.Open(filepath)
+	if err != nil {
+		c.notFound()
+		return
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil || stat.IsDir() {
+		c.notFound()
+		return
+	}
+	if name == "" {
+		name = stat.Name()
+	}
+	c.Writer.Header().Set("ETag", fmt.Sprintf(`"%x-%x"`, stat.ModTime().UnixNano(), stat.Size()))
+	http.ServeContent(c.Writer, c.Req, name, stat.ModTime(), f)
+}
+
+// SPA serves a single-page app from fs under urlPrefix: the existing files are served, and the other paths
+// get the /index.html of fs so the client-side router can handle them. The routes of the engine keep
+// precedence, and the groups with a longer prefix can keep their own 404s with RouterGroup.NotFound.
+// The missing assets, the paths with a file extension or under /static/ or /assets/, are answered
+// by the 404 handlers of the engine instead of the index.
+func (group *RouterGroup) SPA(urlPrefix string, fs http.FileSystem) {
+	spa := group.Group(urlPrefix)
+	spa.NotFound(func(c *Context) {
+		if c.Req.Method != "GET" && c.Req.Method != "HEAD" {
+			c.notFound()
+			return
+		}
+		name := path.Clean("/" + strings.TrimPrefix(c.Req.URL.Path, spa.prefix))
+		if f, err := fs.Open(name); err == nil {
+			defer f.Close()
+			if stat, err := f.Stat(); err == nil && !stat.IsDir() {
+				http.ServeContent(c.Writer, c.Req, stat.Name(), stat.ModTime(), f)
+				return
+			}
+		} else if isAssetPath(name) {
+			c.notFound()
+			return
+		}
+		index, err := fs.Open("/index.html")
+		if err != nil {
+			c.notFound()
+			return
+		}
+		defer index.Close()
+		stat, err := index.Stat()
+		if err != nil {
+			c.notFound()
+			return
+		}
+		http.ServeContent(c.Writer, c.Req, stat.Name(), stat.ModTime(), index)
+	})
+}
+
+// isAssetPath reports whether a path of a single-page app names an asset rather than a client-side route
+func isAssetPath(name string) bool {
+	return strings.HasPrefix(name, "/static/") || strings.HasPrefix(name, "/assets/") || path.Ext(name) != ""
+}
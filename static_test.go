@@ -0,0 +1,181 @@
+package engine
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeFiles creates the files, relative paths to contents, in a new temporary directory
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "engine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestStaticFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"robots.txt": "User-agent: *"})
+	defer os.RemoveAll(dir)
+	e := New()
+	e.StaticFile("/robots.txt", filepath.Join(dir, "robots.txt"))
+	e.StaticFile("/missing.txt", filepath.Join(dir, "missing.txt"))
+
+	w := performRequest(e, "GET", "/robots.txt", nil)
+	if w.Code != 200 || w.Body.String() != "User-agent: *" {
+		t.Errorf("got %d %q", w.Code, w.Body.String())
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if w := performRequest(e, "HEAD", "/robots.txt", nil); w.Code != 200 || w.Body.Len() != 0 {
+		t.Errorf("HEAD: got %d with %d body bytes", w.Code, w.Body.Len())
+	}
+	if w := performRequest(e, "GET", "/missing.txt", nil); w.Code != 404 {
+		t.Errorf("missing file: got %d, want 404", w.Code)
+	}
+}
+
+func TestStatic(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"css/app.css":     "body{}",
+		"docs/index.html": "<h1>docs</h1>",
+		"img/logo.txt":    "logo",
+	})
+	defer os.RemoveAll(dir)
+	middleware := 0
+	e := New()
+	e.NotFound404(func(c *Context) {
+		c.Writer.Write([]byte("custom 404"))
+	})
+	v1 := e.Group("/api").Group("/v1", func(c *Context) {
+		middleware++
+		c.Next()
+	})
+	v1.Static("/assets", dir)
+
+	w := performRequest(e, "GET", "/api/v1/assets/css/app.css", nil)
+	if w.Code != 200 || w.Body.String() != "body{}" || middleware != 1 {
+		t.Errorf("nested prefix: got %d %q, the middleware ran %d times", w.Code, w.Body.String(), middleware)
+	}
+	if w := performRequest(e, "GET", "/api/v1/assets/docs/", nil); w.Body.String() != "<h1>docs</h1>" {
+		t.Errorf("directory index: got %d %q", w.Code, w.Body.String())
+	}
+
+	tests := []string{
+		"/api/v1/assets/css/missing.css",
+		// a directory without index.html isn't listed
+		"/api/v1/assets/img/",
+		"/api/v1/assets/../static_test.go",
+		"/api/v1/assets/%2e%2e/%2e%2e/etc/passwd",
+	}
+	for _, p := range tests {
+		middleware = 0
+		w := performRequest(e, "GET", p, nil)
+		if w.Code != 404 || w.Body.String() != "custom 404" {
+			t.Errorf("GET %s: got %d %q, want the engine 404", p, w.Code, w.Body.String())
+		}
+		if w.Header().Get("X-Content-Type-Options") != "" || strings.Contains(w.Body.String(), "logo.txt") {
+			t.Errorf("GET %s: answered by net/http", p)
+		}
+		if middleware > 1 {
+			t.Errorf("GET %s: the group middleware ran %d times", p, middleware)
+		}
+	}
+}
+
+func TestServeFileWithCache(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"report.csv": "0123456789"})
+	defer os.RemoveAll(dir)
+	e := New()
+	e.NotFound404(func(c *Context) {
+		c.Writer.Write([]byte("custom 404"))
+	})
+	e.GET("/report", func(c *Context) {
+		c.ServeFileWithCache("report.csv", filepath.Join(dir, "report.csv"))
+	})
+	e.GET("/missing", func(c *Context) {
+		c.ServeFileWithCache("", filepath.Join(dir, "missing.csv"))
+	})
+
+	full := performRequest(e, "GET", "/report", nil)
+	etag := full.Header().Get("ETag")
+	if full.Code != 200 || full.Body.String() != "0123456789" || etag == "" {
+		t.Fatalf("full fetch: got %d %q with ETag %q", full.Code, full.Body.String(), etag)
+	}
+	if ct := full.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+
+	ranged := performRequest(e, "GET", "/report", nil, "Range", "bytes=4-")
+	if ranged.Code != 206 || ranged.Body.String() != "456789" || ranged.Header().Get("Content-Range") != "bytes 4-9/10" {
+		t.Errorf("ranged fetch: got %d %q with Content-Range %q", ranged.Code, ranged.Body.String(), ranged.Header().Get("Content-Range"))
+	}
+
+	conditional := performRequest(e, "GET", "/report", nil, "If-None-Match", etag)
+	if conditional.Code != 304 || conditional.Body.Len() != 0 {
+		t.Errorf("If-None-Match: got %d %q, want an empty 304", conditional.Code, conditional.Body.String())
+	}
+	modified := full.Header().Get("Last-Modified")
+	if w := performRequest(e, "GET", "/report", nil, "If-Modified-Since", modified); w.Code != 304 {
+		t.Errorf("If-Modified-Since: status = %d, want 304", w.Code)
+	}
+	if w := performRequest(e, "GET", "/report", nil, "If-None-Match", `"stale"`); w.Code != 200 {
+		t.Errorf("a stale ETag: status = %d, want 200", w.Code)
+	}
+
+	if w := performRequest(e, "GET", "/missing", nil); w.Code != 404 || w.Body.String() != "custom 404" {
+		t.Errorf("missing file: got %d %q, want the engine 404", w.Code, w.Body.String())
+	}
+}
+
+func TestSPA(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"index.html":    "<div id=app></div>",
+		"static/app.js": "app()",
+	})
+	defer os.RemoveAll(dir)
+	e := New()
+	e.NotFound404(func(c *Context) {
+		c.Writer.Write([]byte("custom 404"))
+	})
+	e.GET("/app/api/health", func(c *Context) {
+		c.String(200, "healthy")
+	})
+	e.SPA("/app", http.Dir(dir))
+
+	tests := []struct {
+		path string
+		code int
+		body string
+	}{
+		{"/app/static/app.js", 200, "app()"},
+		{"/app/users/42", 200, "<div id=app></div>"},
+		{"/app/api/health", 200, "healthy"},
+		{"/app/static/missing.js", 404, "custom 404"},
+		{"/app/favicon.ico", 404, "custom 404"},
+		{"/elsewhere", 404, "custom 404"},
+	}
+	for _, tt := range tests {
+		w := performRequest(e, "GET", tt.path, nil)
+		if w.Code != tt.code || w.Body.String() != tt.body {
+			t.Errorf("GET %s: got %d %q, want %d %q", tt.path, w.Code, w.Body.String(), tt.code, tt.body)
+		}
+	}
+	if w := performRequest(e, "POST", "/app/users/42", nil); w.Code != 404 {
+		t.Errorf("POST a client route: status = %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,45 @@
+package engine
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// a task registered with Engine.Every
+type periodicTask struct {
+	interval time.Duration
+	run      func(context.Context)
+}
+
+// Every registers a task run every d while the engine is running with Run, RunTLS or RunWithContext.
+// The context of the task is cancelled when the server shuts down, RunWithContext waits for the
+// running tasks to return. The tasks must be registered before the engine runs.
+func (engine *Engine) Every(d time.Duration, task func(context.Context)) {
+	if d <= 0 {
+		panic("engine: the interval of a periodic task must be positive")
+	}
+	engine.tasks = append(engine.tasks, periodicTask{d, task})
+}
+
+// startTasks starts the periodic tasks until ctx is done, the returned function waits for them to stop
+func (engine *Engine) startTasks(ctx context.Context) func() {
+	var wg sync.WaitGroup
+	for _, task := range engine.tasks {
+		wg.Add(1)
+		go func(task periodicTask) {
+			defer wg.Done()
+			ticker := time.NewTicker(task.interval)
+			defer ticker.Stop()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					task.run(ctx)
+				}
+			}
+		}(task)
+	}
+	return wg.Wait
+}
@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEveryRunsUntilShutdown(t *testing.T) {
+	var runs int32
+	ran := make(chan struct{}, 1)
+	e := New()
+	e.Every(5*time.Millisecond, func(ctx context.Context) {
+		atomic.AddInt32(&runs, 1)
+		select {
+		case ran <- struct{}{}:
+		default:
+		}
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- e.serveWithContext(ctx, e.Server(listener.Addr().String()), listener)
+	}()
+
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("the task never ran")
+	}
+	cancel()
+	if err := <-served; err != nil {
+		t.Fatalf("serveWithContext returned %v", err)
+	}
+	// the tasks are stopped once the server returned
+	stopped := atomic.LoadInt32(&runs)
+	time.Sleep(20 * time.Millisecond)
+	if n := atomic.LoadInt32(&runs); n != stopped {
+		t.Errorf("the task ran %d more times after the shutdown", n-stopped)
+	}
+}
+
+func TestEveryInvalidInterval(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Every(0) didn't panic")
+		}
+	}()
+	New().Every(0, func(context.Context) {})
+}
@@ -0,0 +1,96 @@
+package engine
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// the key under which the timeout state of the request is stored
+const timeoutKey = "timeout"
+
+// timeoutState remembers how the request deadline was built so it can be changed by Context.SetTimeout
+type timeoutState struct {
+	parent context.Context
+	start  time.Time
+	cancel context.CancelFunc
+}
+
+// Timeout returns a middleware giving the request context a deadline d after the request started.
+// Handlers are expected to watch c.Req.Context(), the renderers stop writing once it is done.
+// When the deadline passes and nothing was written, the request is answered with a 503.
+// A route can change its own deadline with Context.SetTimeout().
+func Timeout(d time.Duration) HandlerFunc {
+	return func(c *Context) {
+		state := c.newTimeoutState()
+		c.applyTimeout(state, d)
+		defer func() {
+			state.cancel()
+		}()
+		c.Next()
+
+		if c.Req.Context().Err() == context.DeadlineExceeded && !c.responseStarted() {
+			http.Error(c.Writer, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+		}
+	}
+}
+
+// SetTimeout replaces the deadline of the request with one d after the request started,
+// overriding the one of the Timeout middleware. Call it before the work begins, e.g. in a route middleware,
+// since the contexts derived from the previous request context get cancelled.
+// Without the Timeout middleware, the deadline is released once the response is done.
+func (c *Context) SetTimeout(d time.Duration) {
+	state, ok := c.Keys[timeoutKey].(*timeoutState)
+	if !ok {
+		state = c.newTimeoutState()
+	}
+	if state.cancel != nil {
+		state.cancel()
+	}
+	c.applyTimeout(state, d)
+}
+
+// newTimeoutState stores the timeout state of the request, its deadline is cancelled once the response is done
+func (c *Context) newTimeoutState() *timeoutState {
+	state := &timeoutState{parent: c.Req.Context(), start: time.Now()}
+	c.Set(timeoutKey, state)
+	c.OnResponseDone(func(int, int) {
+		if state.cancel != nil {
+			state.cancel()
+		}
+	})
+	return state
+}
+
+func (c *Context) applyTimeout(state *timeoutState, d time.Duration) {
+	ctx, cancel := context.WithDeadline(state.parent, state.start.Add(d))
+	state.cancel = cancel
+	c.Req = c.Req.WithContext(ctx)
+}
+
+// DeadlineFromHeader returns a middleware giving the request context the deadline sent by the caller
+// in the given header, e.g. "X-Request-Deadline", either as a RFC3339 time or as a duration like "1.5s".
+// Requests without the header pass through, an invalid one is aborted with a 400.
+func DeadlineFromHeader(header string) HandlerFunc {
+	return func(c *Context) {
+		value := c.Req.Header.Get(header)
+		if value == "" {
+			c.Next()
+			return
+		}
+		deadline, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			d, durationErr := time.ParseDuration(value)
+			if durationErr != nil {
+				c.Fail(http.StatusBadRequest, errors.New("invalid "+header+" header: "+value))
+				return
+			}
+			deadline = time.Now().Add(d)
+		}
+		ctx, cancel := context.WithDeadline(c.Req.Context(), deadline)
+		defer cancel()
+		c.Req = c.Req.WithContext(ctx)
+		c.Next()
+	}
+}
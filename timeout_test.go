@@ -0,0 +1,77 @@
+package engine
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSetTimeoutOverridesTimeout(t *testing.T) {
+	slow := func(c *Context) {
+		select {
+		case <-time.After(30 * time.Millisecond):
+			c.String(200, "done")
+		case <-c.Req.Context().Done():
+		}
+	}
+	e := New()
+	e.Use(Timeout(10 * time.Millisecond))
+	e.GET("/report", func(c *Context) {
+		c.SetTimeout(time.Second)
+	}, slow)
+	e.GET("/default", slow)
+
+	w := performRequest(e, "GET", "/report", nil)
+	if w.Code != 200 || w.Body.String() != "done" {
+		t.Errorf("extended timeout: got %d %q, want 200 done", w.Code, w.Body.String())
+	}
+	if w := performRequest(e, "GET", "/default", nil); w.Code != 503 {
+		t.Errorf("global timeout: status = %d, want 503", w.Code)
+	}
+}
+
+func TestDeadlineFromHeader(t *testing.T) {
+	done := make(chan bool, 1)
+	e := New()
+	e.Use(DeadlineFromHeader("X-Request-Deadline"))
+	e.GET("/work", func(c *Context) {
+		_, hasDeadline := c.Req.Context().Deadline()
+		if !hasDeadline {
+			done <- false
+			return
+		}
+		select {
+		case <-c.Req.Context().Done():
+			done <- true
+		case <-time.After(time.Second):
+			done <- false
+		}
+	})
+
+	deadlines := []string{"20ms", time.Now().Add(20 * time.Millisecond).Format(time.RFC3339Nano)}
+	for _, deadline := range deadlines {
+		performRequest(e, "GET", "/work", nil, "X-Request-Deadline", deadline)
+		if !<-done {
+			t.Errorf("deadline %s: the context wasn't done", deadline)
+		}
+	}
+
+	if w := performRequest(e, "GET", "/work", nil, "X-Request-Deadline", "soon"); w.Code != 400 {
+		t.Errorf("invalid header: status = %d, want 400", w.Code)
+	}
+}
+
+func TestSetTimeoutCancelsWhenDone(t *testing.T) {
+	var ctx context.Context
+	e := New()
+	e.GET("/", func(c *Context) {
+		c.SetTimeout(time.Hour)
+		c.SetTimeout(2 * time.Hour)
+		ctx = c.Req.Context()
+		c.String(200, "ok")
+	})
+	performRequest(e, "GET", "/", nil)
+	if ctx.Err() != context.Canceled {
+		t.Errorf("after the response, the context error is %v, want it cancelled", ctx.Err())
+	}
+}
@@ -0,0 +1,177 @@
+package engine
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// the key under which StartTime stores the request start instant
+const StartTimeKey = "startTime"
+
+// StartTime returns a middleware that records when the request started being handled,
+// so later handlers can use Context.Elapsed().
+func StartTime() HandlerFunc {
+	return func(c *Context) {
+		c.Set(StartTimeKey, time.Now())
+		c.Next()
+	}
+}
+
+// Returns the time elapsed since the StartTime middleware ran.
+// It returns 0 if the middleware isn't in the chain.
+func (c *Context) Elapsed() time.Duration {
+	if t, ok := c.Keys[StartTimeKey].(time.Time); ok {
+		return time.Since(t)
+	}
+	return 0
+}
+
+type (
+	// SlowSample describes a request recorded by the SlowRequestTracker middleware
+	SlowSample struct {
+		Method   string        `json:"method"`
+		Path     string        `json:"path"`
+		Status   int           `json:"status"`
+		Duration time.Duration `json:"duration"`
+		Time     time.Time     `json:"time"`
+	}
+
+	// a fixed size ring buffer of the most recent slow requests
+	slowRing struct {
+		mu      sync.Mutex
+		samples []SlowSample
+		next    int
+		full    bool
+	}
+)
+
+func (r *slowRing) add(sample SlowSample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples[r.next] = sample
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *slowRing) list() []SlowSample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.full {
+		return append([]SlowSample(nil), r.samples[:r.next]...)
+	}
+	return append(append([]SlowSample(nil), r.samples[r.next:]...), r.samples[:r.next]...)
+}
+
+// SlowRequestTracker returns a middleware recording the requests taking longer than threshold
+// into a ring buffer keeping the keep most recent ones, see Engine.SlowRequests().
+// The buffer is shared by the whole engine, it is sized by the first tracker recording a request.
+func SlowRequestTracker(threshold time.Duration, keep int) HandlerFunc {
+	return func(c *Context) {
+		start := time.Now()
+		c.Next()
+		if d := time.Since(start); d >= threshold && keep > 0 {
+			c.engine.slowRing(keep).add(SlowSample{
+				Method:   c.Req.Method,
+				Path:     c.Req.URL.Path,
+				Status:   c.Writer.Status(),
+				Duration: d,
+				Time:     start,
+			})
+		}
+	}
+}
+
+// slowRing returns the ring buffer of the engine, creating it with the given size on first use
+func (engine *Engine) slowRing(size int) *slowRing {
+	engine.slowMu.Lock()
+	defer engine.slowMu.Unlock()
+	if engine.slowRequests == nil {
+		engine.slowRequests = &slowRing{samples: make([]SlowSample, size)}
+	}
+	return engine.slowRequests
+}
+
+// Returns the samples recorded by the SlowRequestTracker middleware, oldest first.
+func (engine *Engine) SlowRequests() []SlowSample {
+	engine.slowMu.Lock()
+	ring := engine.slowRequests
+	engine.slowMu.Unlock()
+	if ring == nil {
+		return nil
+	}
+	return ring.list()
+}
+
+type (
+	// ResponseTimeConfig configures the ResponseTimeWithConfig middleware
+	ResponseTimeConfig struct {
+		// Header receives the handling duration, X-Response-Time by default
+		Header string
+
+		// ServerTiming also adds an "app" entry to the Server-Timing header
+		ServerTiming bool
+	}
+
+	// timingWriter sets the timing headers right before the response header is sent
+	timingWriter struct {
+		ResponseWriter
+		start       time.Time
+		config      *ResponseTimeConfig
+		headersDone bool
+	}
+)
+
+func (w *timingWriter) setHeaders() {
+	if w.headersDone {
+		return
+	}
+	w.headersDone = true
+	ms := float64(time.Since(w.start)) / float64(time.Millisecond)
+	w.Header().Set(w.config.Header, fmt.Sprintf("%.2fms", ms))
+	if w.config.ServerTiming {
+		w.Header().Add("Server-Timing", fmt.Sprintf("app;dur=%.2f", ms))
+	}
+}
+
+func (w *timingWriter) WriteHeader(code int) {
+	w.setHeaders()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timingWriter) Write(data []byte) (int, error) {
+	if !w.headersDone {
+		w.WriteHeader(200)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timingWriter) Flush() {
+	w.setHeaders()
+	w.ResponseWriter.Flush()
+}
+
+// ResponseTime returns a middleware setting a X-Response-Time header, e.g. "12.34ms".
+// Since headers can't change once sent, the duration is measured when the handlers first write the response.
+func ResponseTime() HandlerFunc {
+	return ResponseTimeWithConfig(ResponseTimeConfig{})
+}
+
+// Like ResponseTime() with more options, see ResponseTimeConfig.
+func ResponseTimeWithConfig(config ResponseTimeConfig) HandlerFunc {
+	if config.Header == "" {
+		config.Header = "X-Response-Time"
+	}
+	return func(c *Context) {
+		w := &timingWriter{ResponseWriter: c.Writer, start: time.Now(), config: &config}
+		c.Writer = w
+		defer func() {
+			c.Writer = w.ResponseWriter
+		}()
+		c.Next()
+		// nothing written, the header is sent by net/http when the handler returns
+		w.setHeaders()
+	}
+}
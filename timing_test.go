@@ -0,0 +1,97 @@
+package engine
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestElapsedGrows(t *testing.T) {
+	var before, after time.Duration
+	e := New()
+	e.Use(StartTime())
+	e.GET("/", func(c *Context) {
+		before = c.Elapsed()
+		time.Sleep(20 * time.Millisecond)
+		after = c.Elapsed()
+	})
+	performRequest(e, "GET", "/", nil)
+	if after-before < 20*time.Millisecond {
+		t.Errorf("Elapsed went from %v to %v over a 20ms sleep", before, after)
+	}
+}
+
+func TestElapsedWithoutStartTime(t *testing.T) {
+	var elapsed time.Duration = -1
+	e := New()
+	e.GET("/", func(c *Context) {
+		elapsed = c.Elapsed()
+	})
+	performRequest(e, "GET", "/", nil)
+	if elapsed != 0 {
+		t.Errorf("Elapsed = %v without StartTime, want 0", elapsed)
+	}
+}
+
+func TestSlowRequestTracker(t *testing.T) {
+	e := New()
+	e.Use(SlowRequestTracker(20*time.Millisecond, 2))
+	e.GET("/slow/:n", func(c *Context) {
+		time.Sleep(25 * time.Millisecond)
+		c.String(202, "slow")
+	})
+	e.GET("/fast", func(c *Context) {
+		c.String(200, "fast")
+	})
+
+	if samples := e.SlowRequests(); samples != nil {
+		t.Errorf("samples before any request: %v", samples)
+	}
+	performRequest(e, "GET", "/fast", nil)
+	performRequest(e, "GET", "/slow/1", nil)
+	samples := e.SlowRequests()
+	if len(samples) != 1 {
+		t.Fatalf("got %d samples, want 1", len(samples))
+	}
+	s := samples[0]
+	if s.Method != "GET" || s.Path != "/slow/1" || s.Status != 202 || s.Duration < 20*time.Millisecond {
+		t.Errorf("sample = %+v", s)
+	}
+
+	// only the 2 most recent are kept, oldest first
+	performRequest(e, "GET", "/slow/2", nil)
+	performRequest(e, "GET", "/slow/3", nil)
+	samples = e.SlowRequests()
+	if len(samples) != 2 || samples[0].Path != "/slow/2" || samples[1].Path != "/slow/3" {
+		t.Errorf("samples = %+v, want /slow/2 and /slow/3", samples)
+	}
+}
+
+func TestResponseTime(t *testing.T) {
+	e := New()
+	e.Use(ResponseTimeWithConfig(ResponseTimeConfig{ServerTiming: true}))
+	e.GET("/slow", func(c *Context) {
+		time.Sleep(5 * time.Millisecond)
+		c.String(200, "ok")
+	})
+	e.GET("/empty", func(c *Context) {})
+
+	w := performRequest(e, "GET", "/slow", nil)
+	header := w.Header().Get("X-Response-Time")
+	if !strings.HasSuffix(header, "ms") {
+		t.Fatalf("X-Response-Time = %q, want a duration in ms", header)
+	}
+	ms, err := strconv.ParseFloat(strings.TrimSuffix(header, "ms"), 64)
+	if err != nil || ms < 5 || ms > 1000 {
+		t.Errorf("X-Response-Time = %q, want about 5ms", header)
+	}
+	if st := w.Header().Get("Server-Timing"); !strings.HasPrefix(st, "app;dur=") {
+		t.Errorf("Server-Timing = %q", st)
+	}
+
+	w = performRequest(e, "GET", "/empty", nil)
+	if w.Header().Get("X-Response-Time") == "" {
+		t.Error("no X-Response-Time when the handler writes nothing")
+	}
+}
@@ -0,0 +1,48 @@
+package engine
+
+import (
+	"errors"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// HandleNamed is like Handle but also registers the route under name so Engine.URL can build its links.
+func (group *RouterGroup) HandleNamed(name, method, p string, handlers ...HandlerFunc) {
+	engine := group.engine
+	if _, ok := engine.namedRoutes[name]; ok {
+		panic("engine: route name " + name + " is already registered")
+	}
+	group.Handle(method, p, handlers)
+	if engine.namedRoutes == nil {
+		engine.namedRoutes = map[string]string{}
+	}
+	engine.namedRoutes[name] = path.Join(group.prefix, p)
+}
+
+// URL builds the path of the named route, replacing its :param and *catchAll segments with params.
+// It returns an error for unknown names and missing params.
+func (engine *Engine) URL(name string, params map[string]string) (string, error) {
+	pattern, ok := engine.namedRoutes[name]
+	if !ok {
+		return "", errors.New("engine: unknown route name " + name)
+	}
+	segments := strings.Split(pattern, "/")
+	for i, segment := range segments {
+		if segment == "" || (segment[0] != ':' && segment[0] != '*') {
+			continue
+		}
+		value, ok := params[segment[1:]]
+		if !ok {
+			return "", errors.New("engine: missing param " + segment[1:] + " for route " + name)
+		}
+		if segment[0] == ':' {
+			value = url.PathEscape(value)
+		} else {
+			// the catch-all keeps its slashes
+			value = strings.TrimPrefix((&url.URL{Path: value}).EscapedPath(), "/")
+		}
+		segments[i] = value
+	}
+	return strings.Join(segments, "/"), nil
+}
@@ -0,0 +1,38 @@
+package engine
+
+import "testing"
+
+func TestURL(t *testing.T) {
+	e := New()
+	api := e.Group("/api")
+	api.HandleNamed("user", "GET", "/users/:id/posts/:post", func(c *Context) {})
+	e.HandleNamed("files", "GET", "/files/*path", func(c *Context) {})
+
+	tests := []struct {
+		name   string
+		params map[string]string
+		want   string
+	}{
+		{"user", map[string]string{"id": "42", "post": "hello world"}, "/api/users/42/posts/hello%20world"},
+		{"files", map[string]string{"path": "/docs/a b.txt"}, "/files/docs/a%20b.txt"},
+	}
+	for _, tt := range tests {
+		got, err := e.URL(tt.name, tt.params)
+		if err != nil || got != tt.want {
+			t.Errorf("URL(%s) = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	if _, err := e.URL("missing", nil); err == nil {
+		t.Error("URL of an unknown name didn't fail")
+	}
+	if _, err := e.URL("user", map[string]string{"id": "42"}); err == nil {
+		t.Error("URL with a missing param didn't fail")
+	}
+
+	// the built URL is routed back to the route
+	path, _ := e.URL("user", map[string]string{"id": "7", "post": "p"})
+	if w := performRequest(e, "GET", path, nil); w.Code != 200 {
+		t.Errorf("GET %s: status = %d, want 200", path, w.Code)
+	}
+}
@@ -2,13 +2,99 @@ package engine
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
+type (
+	// FieldError describes a field failing to bind or to validate
+	FieldError struct {
+		Field   string `json:"field"`
+		Rule    string `json:"rule"`
+		Message string `json:"message"`
+	}
+
+	// BindErrors lists all the fields failing to bind or to validate, it is the error returned by the binders
+	BindErrors []FieldError
+)
+
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+func (e BindErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fe := range e {
+		messages[i] = fe.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// appendBindErrors adds err to errs, flattening it if it is already a BindErrors
+func appendBindErrors(errs BindErrors, err error) BindErrors {
+	if be, ok := err.(BindErrors); ok {
+		return append(errs, be...)
+	}
+	if fe, ok := err.(FieldError); ok {
+		return append(errs, fe)
+	}
+	return append(errs, FieldError{Message: err.Error()})
+}
+
+// a single rule of a `binding` tag, e.g. "required" or "oneof=a b c"
+type bindingRule struct {
+	name  string
+	param string
+}
+
+// parseBindingTag splits a `binding:"required,oneof=a b c"` tag into its rules
+func parseBindingTag(tag string) []bindingRule {
+	if tag == "" {
+		return nil
+	}
+	var rules []bindingRule
+	for _, part := range strings.Split(tag, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		rule := bindingRule{name: part}
+		if i := strings.Index(part, "="); i >= 0 {
+			rule.name, rule.param = part[:i], part[i+1:]
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// fieldName returns the name used in validation errors, the json or form tag name if any
+func fieldName(field reflect.StructField) string {
+	if j, _ := jsonName(field); j != "" {
+		return j
+	}
+	f := field.Tag.Get("form")
+	if f == "-" {
+		f = ""
+	}
+	return formName(field, f)
+}
+
+// Validate checks the `binding` rules of the struct fields. It doesn't stop at the first violation,
+// every one is recorded with c.Error when c isn't nil and they are all returned as BindErrors.
 func Validate(c *Context, obj interface{}) error {
 
-	var err error
+	var errs BindErrors
+	report := func(field reflect.StructField, rule string, e error) {
+		fe := FieldError{Field: fieldName(field), Rule: rule, Message: e.Error()}
+		errs = append(errs, fe)
+		if c != nil {
+			c.Error(fe, "json validation")
+		}
+	}
 	typ := reflect.TypeOf(obj)
 	val := reflect.ValueOf(obj)
 
@@ -16,30 +102,284 @@ func Validate(c *Context, obj interface{}) error {
 		typ = typ.Elem()
 		val = val.Elem()
 	}
+	if typ.Kind() != reflect.Struct {
+		return nil
+	}
 
 	for i := 0; i < typ.NumField(); i++ {
 		field := typ.Field(i)
+		if field.PkgPath != "" {
+			// unexported, e.g. the internals of time.Time
+			continue
+		}
 		fieldValue := val.Field(i).Interface()
 		zero := reflect.Zero(field.Type).Interface()
 
 		// Validate nested and embedded structes (if pointer, only do so if not nil)
 		if field.Type.Kind() == reflect.Struct ||
 			(field.Type.Kind() == reflect.Ptr && !reflect.DeepEqual(zero, fieldValue)) {
-			err = Validate(c, fieldValue)
+			if e := Validate(c, fieldValue); e != nil {
+				errs = appendBindErrors(errs, e)
+			}
 		}
 
-		if strings.Index(field.Tag.Get("binding"), "required") > -1 {
-			if reflect.DeepEqual(zero, fieldValue) {
-				name := field.Name
-				if j := field.Tag.Get("json"); j != "" {
-					name = j
-				} else if f := field.Tag.Get("form"); f != "" {
-					name = f
+		for _, rule := range parseBindingTag(field.Tag.Get("binding")) {
+			switch rule.name {
+			case "required":
+				if reflect.DeepEqual(zero, fieldValue) {
+					report(field, rule.name, errors.New("Required "+fieldName(field)))
 				}
-				err = errors.New("Required " + name)
-				c.Error(err, "json validation")
+			case "oneof":
+				if e := validateOneOf(val.Field(i), rule.param); e != nil {
+					report(field, rule.name, fmt.Errorf("%s %s", fieldName(field), e))
+				}
+			case "gtfield", "gtefield", "ltfield", "ltefield":
+				if e := validateCrossField(val, val.Field(i), rule); e != nil {
+					report(field, rule.name, fmt.Errorf("%s %s", fieldName(field), e))
+				}
+			case "min", "max", "len":
+				if e := validateBound(val.Field(i), rule); e != nil {
+					report(field, rule.name, fmt.Errorf("%s %s", fieldName(field), e))
+				}
+			case "required_with", "required_without", "required_without_all":
+				if e := validateRequiredIf(val, field, val.Field(i), rule); e != nil {
+					report(field, rule.name, e)
+				}
+			}
+		}
+		if c != nil && c.engine != nil {
+			if e := c.engine.validateEnum(val.Field(i)); e != nil {
+				report(field, "enum", fmt.Errorf("%s %s", fieldName(field), e))
 			}
 		}
 	}
-	return err
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
+
+// RegisterEnum restricts the fields of the string type of example to values, e.g.
+// engine.RegisterEnum(Status(""), []string{"active", "archived"}) makes the binders reject the other Status values.
+// Zero values are accepted, combine with required to reject them.
+func (engine *Engine) RegisterEnum(example interface{}, values []string) {
+	t := reflect.TypeOf(example)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.String {
+		panic("engine: enums must have a string type")
+	}
+	if engine.enums == nil {
+		engine.enums = map[reflect.Type][]string{}
+	}
+	engine.enums[t] = append([]string(nil), values...)
+}
+
+// validateEnum checks v against the values registered for its type, if any
+func (engine *Engine) validateEnum(v reflect.Value) error {
+	v = indirectValue(v)
+	if !v.IsValid() || isZero(v) {
+		return nil
+	}
+	values, ok := engine.enums[v.Type()]
+	if !ok {
+		return nil
+	}
+	for _, value := range values {
+		if v.String() == value {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of [%s], got %q", strings.Join(values, " "), v.String())
+}
+
+// validateOneOf checks that v is one of the space separated values.
+// Zero values are accepted, combine with required to reject them.
+func validateOneOf(v reflect.Value, param string) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if isZero(v) {
+		return nil
+	}
+	allowed := strings.Fields(param)
+	s := fmt.Sprint(v.Interface())
+	for _, a := range allowed {
+		if s == a {
+			return nil
+		}
+	}
+	return fmt.Errorf("must be one of [%s], got %q", strings.Join(allowed, " "), s)
+}
+
+// isZero reports whether v holds the zero value of its type
+func isZero(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+// validateRequiredIf checks the conditional required rules, the parameter is a space separated list of sibling fields:
+// required_with=A B requires the field when A or B is set, required_without=A B when A or B is missing
+// and required_without_all=A B when both are missing, so a field must be present in the group.
+func validateRequiredIf(parent reflect.Value, field reflect.StructField, v reflect.Value, rule bindingRule) error {
+	if !isZero(v) {
+		return nil
+	}
+	var present, missing []string
+	names := strings.Fields(rule.param)
+	for _, name := range names {
+		sibling, ok := parent.Type().FieldByName(name)
+		if !ok {
+			return fmt.Errorf("%s references unknown field %s", fieldName(field), name)
+		}
+		if isZero(parent.FieldByIndex(sibling.Index)) {
+			missing = append(missing, fieldName(sibling))
+		} else {
+			present = append(present, fieldName(sibling))
+		}
+	}
+	switch {
+	case rule.name == "required_with" && len(present) > 0:
+		return fmt.Errorf("Required %s when %s is present", fieldName(field), strings.Join(present, ", "))
+	case rule.name == "required_without" && len(missing) > 0:
+		return fmt.Errorf("Required %s when %s is missing", fieldName(field), strings.Join(missing, ", "))
+	case rule.name == "required_without_all" && len(missing) == len(names):
+		return fmt.Errorf("Required one of [%s %s]", fieldName(field), strings.Join(missing, " "))
+	}
+	return nil
+}
+
+var boundOperators = map[string]string{
+	"min": ">=",
+	"max": "<=",
+	"len": "exactly",
+}
+
+// validateBound checks the min, max and len rules: the length of strings (in characters), slices and maps,
+// and the value of numbers, e.g. `binding:"min=0,max=130"`.
+// The numbers are always checked, 0 included, while the empty strings and the nil pointers are accepted
+// as absent values, combine with required to reject them.
+func validateBound(v reflect.Value, rule bindingRule) error {
+	v = indirectValue(v)
+	if !v.IsValid() || v.Kind() == reflect.String && v.Len() == 0 {
+		return nil
+	}
+	bound, err := strconv.ParseFloat(rule.param, 64)
+	if err != nil {
+		return fmt.Errorf("has an invalid %s bound %q", rule.name, rule.param)
+	}
+	var value float64
+	what := "must be"
+	switch v.Kind() {
+	case reflect.String:
+		value, what = float64(utf8.RuneCountInString(v.String())), "length must be"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		value, what = float64(v.Len()), "length must be"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		value = v.Float()
+	default:
+		return fmt.Errorf("can't be checked with %s", rule.name)
+	}
+	cmp := compareFloats(value, bound)
+	if (rule.name == "min" && cmp < 0) || (rule.name == "max" && cmp > 0) || (rule.name == "len" && cmp != 0) {
+		return fmt.Errorf("%s %s %s", what, boundOperators[rule.name], rule.param)
+	}
+	return nil
+}
+
+var crossFieldOperators = map[string]string{
+	"gtfield":  ">",
+	"gtefield": ">=",
+	"ltfield":  "<",
+	"ltefield": "<=",
+}
+
+// validateCrossField compares v with the sibling field of the struct named by the rule parameter,
+// e.g. `binding:"gtefield=StartDate"`. The rule is skipped when one of the values is zero.
+func validateCrossField(parent, v reflect.Value, rule bindingRule) error {
+	other := parent.FieldByName(rule.param)
+	if !other.IsValid() {
+		return fmt.Errorf("references unknown field %s", rule.param)
+	}
+	v, other = indirectValue(v), indirectValue(other)
+	if !v.IsValid() || !other.IsValid() || isZero(v) || isZero(other) {
+		return nil
+	}
+	cmp, err := compareValues(v, other)
+	if err != nil {
+		return err
+	}
+	var ok bool
+	switch rule.name {
+	case "gtfield":
+		ok = cmp > 0
+	case "gtefield":
+		ok = cmp >= 0
+	case "ltfield":
+		ok = cmp < 0
+	case "ltefield":
+		ok = cmp <= 0
+	}
+	if !ok {
+		return fmt.Errorf("must be %s %s", crossFieldOperators[rule.name], rule.param)
+	}
+	return nil
+}
+
+// indirectValue follows pointers, it returns an invalid value for nil ones
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return reflect.Value{}
+		}
+		v = v.Elem()
+	}
+	return v
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// compareValues returns -1, 0 or 1 when a is less, equal or greater than b
+func compareValues(a, b reflect.Value) (int, error) {
+	if a.Type() == timeType && b.Type() == timeType {
+		ta, tb := a.Interface().(time.Time), b.Interface().(time.Time)
+		switch {
+		case ta.Before(tb):
+			return -1, nil
+		case ta.After(tb):
+			return 1, nil
+		}
+		return 0, nil
+	}
+	if a.Kind() != b.Kind() {
+		return 0, fmt.Errorf("can't be compared with a %s", b.Type())
+	}
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return compareFloats(float64(a.Int()), float64(b.Int())), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return compareFloats(float64(a.Uint()), float64(b.Uint())), nil
+	case reflect.Float32, reflect.Float64:
+		return compareFloats(a.Float(), b.Float()), nil
+	case reflect.String:
+		return strings.Compare(a.String(), b.String()), nil
+	}
+	return 0, fmt.Errorf("can't be compared, unsupported type %s", a.Type())
+}
+
+func compareFloats(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
 }
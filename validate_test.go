@@ -0,0 +1,248 @@
+package engine
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// bindJSON binds body with ParseBody in a request served by e and returns the error
+func bindJSON(e *Engine, body string, item interface{}) error {
+	var err error
+	e.POST("/bind", func(c *Context) {
+		err = c.ParseBody(item)
+	})
+	performRequest(e, "POST", "/bind", strings.NewReader(body), "Content-Type", MIMEJSON)
+	return err
+}
+
+func TestOneOfValidation(t *testing.T) {
+	type order struct {
+		Status string `json:"status" binding:"oneof=pending paid shipped"`
+	}
+	var valid order
+	if err := bindJSON(New(), `{"status":"paid"}`, &valid); err != nil || valid.Status != "paid" {
+		t.Errorf("valid value: got %+v, %v", valid, err)
+	}
+
+	var invalid order
+	err := bindJSON(New(), `{"status":"lost"}`, &invalid)
+	errs, ok := err.(BindErrors)
+	if !ok || len(errs) != 1 || errs[0].Rule != "oneof" || errs[0].Field != "status" {
+		t.Fatalf("invalid value: got %#v, want a oneof error on status", err)
+	}
+	if !strings.Contains(errs[0].Message, "pending paid shipped") {
+		t.Errorf("the message %q doesn't list the allowed values", errs[0].Message)
+	}
+}
+
+func TestCrossFieldValidation(t *testing.T) {
+	type booking struct {
+		StartDate time.Time `json:"start_date"`
+		EndDate   time.Time `json:"end_date" binding:"gtefield=StartDate"`
+	}
+	var valid booking
+	err := bindJSON(New(), `{"start_date":"2020-01-01T00:00:00Z","end_date":"2020-01-05T00:00:00Z"}`, &valid)
+	if err != nil {
+		t.Errorf("EndDate after StartDate: got %v", err)
+	}
+
+	var invalid booking
+	err = bindJSON(New(), `{"start_date":"2020-01-05T00:00:00Z","end_date":"2020-01-01T00:00:00Z"}`, &invalid)
+	errs, ok := err.(BindErrors)
+	if !ok || len(errs) != 1 || errs[0].Rule != "gtefield" || errs[0].Field != "end_date" {
+		t.Fatalf("EndDate before StartDate: got %#v, want a gtefield error on end_date", err)
+	}
+	if !strings.Contains(errs[0].Message, "StartDate") {
+		t.Errorf("the message %q doesn't name StartDate", errs[0].Message)
+	}
+}
+
+func TestBindErrorsListEveryField(t *testing.T) {
+	type signup struct {
+		Email string `json:"email" binding:"required"`
+		Age   int    `json:"age" binding:"min=18"`
+		Role  string `json:"role" binding:"oneof=user admin"`
+		Name  string `json:"name"`
+	}
+	var form signup
+	err := bindJSON(New(), `{"age":12,"role":"root","name":"ann"}`, &form)
+	errs, ok := err.(BindErrors)
+	if !ok || len(errs) != 3 {
+		t.Fatalf("got %#v, want 3 errors", err)
+	}
+	for i, field := range []string{"email", "age", "role"} {
+		if errs[i].Field != field {
+			t.Errorf("error %d is on %q, want %q", i, errs[i].Field, field)
+		}
+	}
+}
+
+func TestFieldErrorNamesWithoutTagOptions(t *testing.T) {
+	type signup struct {
+		Email string `json:"email,omitempty" binding:"required"`
+		Role  string `json:"role,omitempty" binding:"oneof=user admin"`
+		Code  string `json:"-" form:"code" binding:"required"`
+		Token string `json:"-" binding:"required"`
+	}
+	var form signup
+	err := bindJSON(New(), `{"role":"root"}`, &form)
+	errs, ok := err.(BindErrors)
+	if !ok || len(errs) != 4 {
+		t.Fatalf("got %#v, want 4 errors", err)
+	}
+	for i, field := range []string{"email", "role", "code", "Token"} {
+		if errs[i].Field != field {
+			t.Errorf("error %d is on %q, want %q", i, errs[i].Field, field)
+		}
+		if !strings.HasPrefix(errs[i].Message, field+" ") && !strings.HasPrefix(errs[i].Message, "Required "+field) {
+			t.Errorf("error %d: the message %q doesn't name %s", i, errs[i].Message, field)
+		}
+	}
+}
+
+func TestBindErrorsMixConversionAndValidation(t *testing.T) {
+	type filter struct {
+		Page  int    `form:"page"`
+		Limit int    `form:"limit" binding:"max=100"`
+		Sort  string `form:"sort" binding:"required"`
+	}
+	var f filter
+	err := bindQuery(New(), "/bind?page=first&limit=500", &f)
+	errs, ok := err.(BindErrors)
+	if !ok || len(errs) != 3 {
+		t.Fatalf("got %#v, want 3 errors", err)
+	}
+	rules := map[string]string{}
+	for _, e := range errs {
+		rules[e.Field] = e.Rule
+	}
+	if rules["page"] != "type" || rules["limit"] != "max" || rules["sort"] != "required" {
+		t.Errorf("got the rules %v", rules)
+	}
+}
+
+func TestRequiredGroupValidation(t *testing.T) {
+	type search struct {
+		Name  string `form:"name" binding:"required_without_all=Email Phone"`
+		Email string `form:"email"`
+		Phone string `form:"phone"`
+	}
+	var none search
+	err := bindQuery(New(), "/bind", &none)
+	errs, ok := err.(BindErrors)
+	if !ok || len(errs) != 1 || errs[0].Rule != "required_without_all" || errs[0].Field != "name" {
+		t.Fatalf("no filter: got %#v, want a required_without_all error on name", err)
+	}
+	if !strings.Contains(errs[0].Message, "name email phone") {
+		t.Errorf("the message %q doesn't name the group", errs[0].Message)
+	}
+
+	for _, url := range []string{"/bind?phone=555", "/bind?name=ann"} {
+		var one search
+		if err := bindQuery(New(), url, &one); err != nil {
+			t.Errorf("%s: got %v", url, err)
+		}
+	}
+}
+
+func TestRequiredWithValidation(t *testing.T) {
+	type period struct {
+		From string `json:"from"`
+		To   string `json:"to" binding:"required_with=From"`
+	}
+	var partial, empty period
+	if err := bindJSON(New(), `{"from":"monday"}`, &partial); err == nil {
+		t.Error("from without to passed")
+	}
+	if err := bindJSON(New(), `{}`, &empty); err != nil {
+		t.Errorf("neither from nor to: got %v", err)
+	}
+}
+
+func TestBoundValidation(t *testing.T) {
+	type profile struct {
+		Age  int     `json:"age" binding:"min=1,max=130"`
+		Name string  `json:"name" binding:"min=2,max=5"`
+		Code string  `json:"code" binding:"len=3"`
+		Rate float64 `json:"rate" binding:"max=1"`
+	}
+	tests := []struct {
+		body   string
+		failed string
+	}{
+		{`{"age":30,"name":"ann","code":"abc","rate":0.5}`, ""},
+		{`{"age":1,"name":"an","code":"abc"}`, ""},
+		// a zero value is checked too
+		{`{"age":0,"name":"ann"}`, "age"},
+		{`{"age":131,"name":"ann"}`, "age"},
+		{`{"age":30,"name":"a"}`, "name"},
+		{`{"age":30,"name":"annabel"}`, "name"},
+		{`{"age":30,"code":"ab"}`, "code"},
+		{`{"age":30,"rate":1.5}`, "rate"},
+	}
+	for _, tt := range tests {
+		var p profile
+		err := bindJSON(New(), tt.body, &p)
+		if tt.failed == "" {
+			if err != nil {
+				t.Errorf("%s: got %v", tt.body, err)
+			}
+			continue
+		}
+		errs, ok := err.(BindErrors)
+		if !ok || len(errs) != 1 || errs[0].Field != tt.failed {
+			t.Errorf("%s: got %#v, want an error on %s", tt.body, err, tt.failed)
+		}
+	}
+
+	var p profile
+	errs, _ := bindJSON(New(), `{"age":0}`, &p).(BindErrors)
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "age must be >= 1") {
+		t.Errorf("got %v, want a descriptive message", errs)
+	}
+}
+
+// accountStatus is an enum registered in the tests
+type accountStatus string
+
+func TestRegisterEnum(t *testing.T) {
+	type account struct {
+		Status  accountStatus  `json:"status" form:"status"`
+		Pending *accountStatus `json:"pending"`
+		Note    string         `json:"note"`
+	}
+	newEngine := func() *Engine {
+		e := New()
+		e.RegisterEnum(accountStatus(""), []string{"active", "archived"})
+		return e
+	}
+
+	var valid account
+	if err := bindJSON(newEngine(), `{"status":"active","pending":"archived","note":"x"}`, &valid); err != nil {
+		t.Errorf("registered values: got %v", err)
+	}
+	var empty account
+	if err := bindJSON(newEngine(), `{}`, &empty); err != nil {
+		t.Errorf("zero values: got %v", err)
+	}
+
+	var invalid account
+	err := bindJSON(newEngine(), `{"status":"deleted","pending":"nope"}`, &invalid)
+	errs, ok := err.(BindErrors)
+	if !ok || len(errs) != 2 || errs[0].Field != "status" || errs[1].Field != "pending" {
+		t.Fatalf("got %#v, want errors on status and pending", err)
+	}
+	if !strings.Contains(errs[0].Message, "active archived") {
+		t.Errorf("the message %q doesn't list the values", errs[0].Message)
+	}
+
+	var query account
+	if err := bindQuery(newEngine(), "/bind?status=deleted", &query); err == nil {
+		t.Error("the query binder accepted an unregistered value")
+	}
+	var unregistered account
+	if err := bindJSON(New(), `{"status":"deleted"}`, &unregistered); err != nil {
+		t.Errorf("without RegisterEnum: got %v", err)
+	}
+}